@@ -0,0 +1,107 @@
+package ghch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultAPIURL = "https://api.github.com/"
+
+const defaultWebURL = "https://github.com"
+
+// httpTimeout bounds each HTTP request, so that a stalled server doesn't
+// hang the run
+const httpTimeout = 30 * time.Second
+
+// httpClient is for requests out of the API client, such as webhooks
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// getAPIURL returns the API root ending with a slash
+func (gh *ghch) getAPIURL() string {
+	if gh.apiURL == "" {
+		return defaultAPIURL
+	}
+	return strings.TrimSuffix(gh.apiURL, "/") + "/"
+}
+
+// getWebURL returns the root of links in the output without a trailing slash
+func (gh *ghch) getWebURL() string {
+	if gh.webURL == "" {
+		return defaultWebURL
+	}
+	return strings.TrimSuffix(gh.webURL, "/")
+}
+
+var nextLinkReg = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// apiRequest calls GitHub API endpoints which go-octokit does not cover.
+// path is relative to the API root unless it is an absolute URL, such as the
+// returned next page link.
+func (gh *ghch) apiRequest(method, path string, body, v interface{}) (next string, err error) {
+	u := path
+	if !strings.HasPrefix(path, "https://") && !strings.HasPrefix(path, "http://") {
+		u = gh.getAPIURL() + strings.TrimPrefix(path, "/")
+	}
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return "", errors.Wrapf(err, "failed to encode request to %s", path)
+		}
+	}
+	req, err := http.NewRequest(method, u, &reqBody)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to request %s", path)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if gh.token != "" {
+		req.Header.Set("Authorization", "token "+gh.token)
+	}
+	client := gh.httpClient
+	if client == nil {
+		client = httpClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to request %s", path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return "", &apiError{StatusCode: resp.StatusCode, Method: method, Path: path, Body: strings.TrimSpace(string(b))}
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return "", errors.Wrapf(err, "failed to decode response of %s", path)
+		}
+	}
+	if matches := nextLinkReg.FindStringSubmatch(resp.Header.Get("Link")); len(matches) > 1 {
+		next = matches[1]
+	}
+	return next, nil
+}
+
+func (gh *ghch) apiGet(path string, v interface{}) (next string, err error) {
+	return gh.apiRequest("GET", path, nil, v)
+}
+
+type apiError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Body       string
+}
+
+func (e *apiError) Error() string {
+	return e.Method + " " + e.Path + ": " + http.StatusText(e.StatusCode) + ": " + e.Body
+}
@@ -0,0 +1,23 @@
+package ghch
+
+import "testing"
+
+func TestAPIAndWebURL(t *testing.T) {
+	testCases := []struct {
+		apiURL, webURL       string
+		expectAPI, expectWeb string
+	}{
+		{"", "", "https://api.github.com/", "https://github.com"},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com", "https://ghe.example.com/api/v3/", "https://ghe.example.com"},
+		{"https://ghe.example.com/api/v3/", "https://ghe.example.com/", "https://ghe.example.com/api/v3/", "https://ghe.example.com"},
+	}
+	for _, tc := range testCases {
+		gh := &ghch{apiURL: tc.apiURL, webURL: tc.webURL}
+		if got := gh.getAPIURL(); got != tc.expectAPI {
+			t.Errorf("getAPIURL() of %q = %q, expect %q", tc.apiURL, got, tc.expectAPI)
+		}
+		if got := gh.getWebURL(); got != tc.expectWeb {
+			t.Errorf("getWebURL() of %q = %q, expect %q", tc.webURL, got, tc.expectWeb)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package ghch
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// apiStats counts API calls and cache lookups of a run to size tokens and
+// caches of large automated runs. The methods are safe on nil.
+type apiStats struct {
+	mu        sync.Mutex
+	calls     int
+	prHits    int
+	prLookups int
+	secHits   int
+	secLooks  int
+	// the latest rate limit headers, if any response had them
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// apiTransport counts requests through it and records their rate limits
+type apiTransport struct {
+	base  http.RoundTripper
+	stats *apiStats
+}
+
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	t.stats.record(resp)
+	return resp, err
+}
+
+func (st *apiStats) record(resp *http.Response) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.calls++
+	if resp == nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	st.remaining = remaining
+	st.limit, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if sec, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		st.reset = time.Unix(sec, 0)
+	}
+}
+
+// lookedUpPR counts a lookup of a pull request in the cache
+func (st *apiStats) lookedUpPR(hit bool) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.prLookups++
+	if hit {
+		st.prHits++
+	}
+}
+
+// lookedUpSection counts a lookup of a section in the cache
+func (st *apiStats) lookedUpSection(hit bool) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.secLooks++
+	if hit {
+		st.secHits++
+	}
+}
+
+// summary is like "12 API calls, cache hits: 30/40 (75%) pull requests,
+// 3/4 (75%) sections, rate limit: 4988/5000 remaining until 15:04:05"
+func (st *apiStats) summary() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	str := fmt.Sprintf("%d API calls", st.calls)
+	if st.prLookups > 0 || st.secLooks > 0 {
+		str += fmt.Sprintf(", cache hits: %s pull requests, %s sections",
+			hitRate(st.prHits, st.prLookups), hitRate(st.secHits, st.secLooks))
+	}
+	if st.limit > 0 {
+		str += fmt.Sprintf(", rate limit: %d/%d remaining", st.remaining, st.limit)
+		if !st.reset.IsZero() {
+			str += " until " + st.reset.Local().Format("15:04:05")
+		}
+	}
+	return str
+}
+
+func hitRate(hits, lookups int) string {
+	if lookups == 0 {
+		return "0/0"
+	}
+	return fmt.Sprintf("%d/%d (%d%%)", hits, lookups, hits*100/lookups)
+}
+
+// newAPIClient returns the client counting its requests in stats
+func newAPIClient(stats *apiStats) *http.Client {
+	return &http.Client{Transport: &apiTransport{base: http.DefaultTransport, stats: stats}, Timeout: httpTimeout}
+}
+
+// reportAPIStats logs the API usage of the run when asked
+func (gh *ghch) reportAPIStats() {
+	if gh.showAPIStats && gh.apiStats != nil {
+		log.Print(gh.apiStats.summary())
+	}
+}
@@ -0,0 +1,39 @@
+package ghch
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAPIStatsSummary(t *testing.T) {
+	st := &apiStats{}
+	if got, expect := st.summary(), "0 API calls"; got != expect {
+		t.Errorf("got %q, expect %q", got, expect)
+	}
+
+	st.record(&http.Response{Header: http.Header{}})
+	st.record(&http.Response{Header: http.Header{
+		"X-Ratelimit-Limit":     {"5000"},
+		"X-Ratelimit-Remaining": {"4998"},
+		"X-Ratelimit-Reset":     {"1717400000"},
+	}})
+	// failed requests count as well
+	st.record(nil)
+	st.lookedUpPR(true)
+	st.lookedUpPR(true)
+	st.lookedUpPR(true)
+	st.lookedUpPR(false)
+	st.lookedUpSection(false)
+	expect := "3 API calls, cache hits: 3/4 (75%) pull requests, 0/1 (0%) sections, " +
+		"rate limit: 4998/5000 remaining until " + time.Unix(1717400000, 0).Local().Format("15:04:05")
+	if got := st.summary(); got != expect {
+		t.Errorf("got %q, expect %q", got, expect)
+	}
+
+	// ghch built without initialize has no stats
+	var nilStats *apiStats
+	nilStats.record(nil)
+	nilStats.lookedUpPR(true)
+	nilStats.lookedUpSection(true)
+}
@@ -0,0 +1,181 @@
+package ghch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// runBackfill merges generated sections of all versions into an existing,
+// hand-written changelog. The prose of each version is kept, followed by the
+// pull requests it doesn't mention yet. Versions ghch doesn't know, such as
+// those before moving to GitHub, are kept where they are.
+func (cli *CLI) runBackfill(argv []string) int {
+	opts := &ghOpts{}
+	p, args, err := parseCommandArgs("backfill", opts, argv)
+	if err != nil {
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+	if len(args) > 0 {
+		opts.Changelog = args[0]
+	}
+	opts.All = true
+
+	gh, err := opts.newGhch()
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	rdr, err := opts.newRenderer(gh.config)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	path := gh.repoFile(opts.Changelog)
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Print(errors.Wrap(err, "failed to read changelog"))
+		return exitCodeErr
+	}
+	merged, err := backfillChangelog(normalizeNewlines(string(orig)), gh.getChangelog(opts.NextVersion), rdr)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	if strings.Contains(string(orig), "\r\n") {
+		merged = strings.Replace(merged, "\n", "\r\n", -1)
+	}
+
+	switch {
+	case opts.DryRun:
+		diff, err := gh.diff(path, string(orig), merged)
+		if err != nil {
+			log.Print(err)
+			return exitCodeErr
+		}
+		fmt.Fprint(cli.OutStream, diff)
+	case opts.Write:
+		if err := ioutil.WriteFile(path, []byte(merged), 0644); err != nil {
+			log.Print(errors.Wrap(err, "failed to write changelog"))
+			return exitCodeErr
+		}
+	default:
+		fmt.Fprint(cli.OutStream, merged)
+	}
+	return gh.exitCode(false, false)
+}
+
+// backfillEntry is a version section of the merged changelog
+type backfillEntry struct {
+	version string
+	text    string
+}
+
+func backfillChangelog(orig string, chlog Changelog, rdr *renderer) (string, error) {
+	preamble, sections := splitChangelog(orig)
+	var written []backfillEntry
+	if sections != "" {
+		for _, text := range strings.Split("\n"+sections, "\n## ")[1:] {
+			text = "## " + strings.TrimSpace(text)
+			written = append(written, backfillEntry{version: headingVersion(text), text: text})
+		}
+	}
+
+	var entries []backfillEntry
+	pos := make(map[string]int)
+	for _, s := range chlog.Sections {
+		ver := backfillKey(s.ToRevision)
+		var prose string
+		for _, w := range written {
+			if w.version == ver {
+				prose = w.text
+				break
+			}
+		}
+		if prose != "" {
+			listed := listedPRNums(prose)
+			s.removePRs(func(pr *PullRequest) bool {
+				return listed[pr.Number]
+			})
+		}
+		str, err := rdr.section(s)
+		if err != nil {
+			return "", err
+		}
+		str = strings.TrimSpace(str)
+		if prose != "" {
+			if body := sectionBody(str); !s.isEmpty() && body != "" {
+				str = prose + "\n\n" + body
+			} else {
+				str = prose
+			}
+		} else if s.isEmpty() && isUnreleased(s.ToRevision) {
+			// nothing has been changed since the latest release
+			continue
+		}
+		pos[ver] = len(entries)
+		entries = append(entries, backfillEntry{version: ver, text: str})
+	}
+
+	// insert versions only in the changelog after the one preceding them
+	last := -1
+	for _, w := range written {
+		if i, ok := pos[w.version]; ok {
+			last = i
+			continue
+		}
+		last++
+		entries = append(entries[:last], append([]backfillEntry{w}, entries[last:]...)...)
+		for v, i := range pos {
+			if i >= last {
+				pos[v] = i + 1
+			}
+		}
+	}
+
+	var texts []string
+	if preamble == "" {
+		preamble = defaultChangelogHeader
+	}
+	texts = append(texts, preamble)
+	for _, e := range entries {
+		texts = append(texts, e.text)
+	}
+	return strings.Join(texts, "\n\n") + "\n", nil
+}
+
+// headingVersion returns the version of the "## " heading starting text
+func headingVersion(text string) string {
+	heading := strings.TrimSpace(strings.TrimPrefix(strings.SplitN(text, "\n", 2)[0], "## "))
+	h, ok := parseSectionHeading(heading)
+	if !ok {
+		return ""
+	}
+	return backfillKey(h.version)
+}
+
+// backfillKey lets "v1.2.0" match "1.2.0", and "Unreleased" the changes
+// since the latest version
+func backfillKey(ver string) string {
+	if isUnreleased(ver) {
+		return "unreleased"
+	}
+	ver = strings.ToLower(ver)
+	if len(ver) > 1 && ver[0] == 'v' && ver[1] >= '0' && ver[1] <= '9' {
+		return ver[1:]
+	}
+	return ver
+}
+
+// sectionBody strips the heading off a rendered section
+func sectionBody(str string) string {
+	kv := strings.SplitN(str, "\n", 2)
+	if len(kv) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(kv[1])
+}
@@ -0,0 +1,117 @@
+package ghch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestBackfillChangelog(t *testing.T) {
+	orig := "# Changelog\n" +
+		"\n" +
+		"All notable changes.\n" +
+		"\n" +
+		"## 0.2.0 (2016-05-01)\n" +
+		"\n" +
+		"Rewrote the parser. Thanks everyone!\n" +
+		"\n" +
+		"* Fix parsing [#2](https://github.com/o/r/pull/2)\n" +
+		"\n" +
+		"## 0.1.0 (2016-03-01)\n" +
+		"\n" +
+		"First release before moving to GitHub.\n"
+	pr := func(num int, title string) *PullRequest {
+		return &PullRequest{PullRequest: &octokit.PullRequest{
+			Number: num, Title: title, User: octokit.User{Login: "Songmu"},
+			HTMLURL: fmt.Sprintf("https://github.com/o/r/pull/%d", num),
+		}}
+	}
+	at := time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC)
+	chlog := Changelog{Sections: []Section{
+		{ToRevision: "v0.3.0", Owner: "o", Repo: "r", ChangedAt: at, PullRequests: []*PullRequest{pr(3, "Add --all")}},
+		{ToRevision: "v0.2.0", Owner: "o", Repo: "r", ChangedAt: at, PullRequests: []*PullRequest{pr(2, "Fix parsing"), pr(1, "Add tests")}},
+	}}
+	got, err := backfillChangelog(orig, chlog, &renderer{tmpl: mdTmpl})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := func(str string) int {
+		i := strings.Index(got, str)
+		if i < 0 {
+			t.Errorf("%q should be in the merged changelog", str)
+		}
+		return i
+	}
+	if !strings.HasPrefix(got, "# Changelog\n\nAll notable changes.\n\n## [v0.3.0]") {
+		t.Errorf("generated sections should follow the preamble")
+	}
+	order := []int{idx("Add --all"), idx("## 0.2.0 (2016-05-01)"), idx("Rewrote the parser"), idx("Add tests"), idx("## 0.1.0")}
+	for i := 1; i < len(order); i++ {
+		if order[i-1] > order[i] {
+			t.Errorf("unexpected order of entries: %v", order)
+		}
+	}
+	if n := strings.Count(got, "Fix parsing"); n != 1 {
+		t.Errorf("pull requests already listed should not be added again, but found %d times", n)
+	}
+	if strings.Contains(got, "## [v0.2.0]") {
+		t.Error("the hand-written heading should be kept")
+	}
+}
+
+func TestBackfillKey(t *testing.T) {
+	for in, want := range map[string]string{"v1.2.0": "1.2.0", "1.2.0": "1.2.0", "Unreleased": "unreleased", "": "unreleased", "vNext": "vnext"} {
+		if got := backfillKey(in); got != want {
+			t.Errorf("backfillKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBackfillKeepAChangelog(t *testing.T) {
+	orig := "# Changelog\n" +
+		"\n" +
+		"## [Unreleased]\n" +
+		"\n" +
+		"## [0.2.0] - 2016-05-01\n" +
+		"\n" +
+		"### Fixed\n" +
+		"\n" +
+		"- Fix parsing [#2](https://github.com/o/r/pull/2)\n" +
+		"\n" +
+		"## 0.1.0 - 2016-03-01\n" +
+		"\n" +
+		"### Added\n" +
+		"\n" +
+		"- First release\n" +
+		"\n" +
+		"[Unreleased]: https://github.com/o/r/compare/v0.2.0...HEAD\n" +
+		"[0.2.0]: https://github.com/o/r/compare/v0.1.0...v0.2.0\n"
+	pr := &PullRequest{PullRequest: &octokit.PullRequest{
+		Number: 1, Title: "Add tests", User: octokit.User{Login: "Songmu"}, HTMLURL: "https://github.com/o/r/pull/1",
+	}}
+	at := time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC)
+	chlog := Changelog{Sections: []Section{
+		{ToRevision: "", Owner: "o", Repo: "r", ChangedAt: at},
+		{ToRevision: "v0.2.0", Owner: "o", Repo: "r", ChangedAt: at, PullRequests: []*PullRequest{pr}},
+		{ToRevision: "v0.1.0", Owner: "o", Repo: "r", ChangedAt: at},
+	}}
+	got, err := backfillChangelog(orig, chlog, &renderer{tmpl: mdTmpl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, heading := range []string{"## [Unreleased]\n", "## [0.2.0] - 2016-05-01\n", "## 0.1.0 - 2016-03-01\n"} {
+		if n := strings.Count(got, heading); n != 1 {
+			t.Errorf("%q should be kept once, but found %d times:\n%s", heading, n, got)
+		}
+	}
+	if strings.Contains(got, "## [v0.2.0]") || strings.Contains(got, "## [v0.1.0]") {
+		t.Errorf("versions already written should not be generated again:\n%s", got)
+	}
+	if i, j := strings.Index(got, "## [0.2.0]"), strings.Index(got, "Add tests"); j < i || j > strings.Index(got, "## 0.1.0") {
+		t.Errorf("missing pull requests should be added to their version:\n%s", got)
+	}
+}
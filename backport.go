@@ -0,0 +1,109 @@
+package ghch
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Backport describes where a cherry-picked or backported change came from
+type Backport struct {
+	PullRequest int    `json:"pull_request,omitempty"`
+	Commit      string `json:"commit,omitempty"`
+	Release     string `json:"release,omitempty"`
+}
+
+var (
+	cherryPickReg = regexp.MustCompile(`\(cherry picked from commit ([0-9a-f]{7,40})\)`)
+	// matches backport bot styles, e.g. "Backport #123", "Backport of #123"
+	// and "Backport 1a2b3c4 from #123"
+	backportReg = regexp.MustCompile(`(?i)\bbackport(?:ed)?(?:\s+of)?(?:\s+[0-9a-f]{7,40}\s+from)?\s+#([0-9]+)`)
+)
+
+func parseBackport(msg string) *Backport {
+	var bp Backport
+	if matches := backportReg.FindStringSubmatch(msg); len(matches) > 1 {
+		bp.PullRequest, _ = strconv.Atoi(matches[1])
+	}
+	if matches := cherryPickReg.FindStringSubmatch(msg); len(matches) > 1 {
+		bp.Commit = matches[1]
+	}
+	if bp.PullRequest == 0 && bp.Commit == "" {
+		return nil
+	}
+	return &bp
+}
+
+func (gh *ghch) annotateBackports(s *Section) {
+	for _, pr := range s.PullRequests {
+		msg := pr.Title + "\n" + pr.Body
+		if pr.MergeCommitSha != "" {
+			out, _ := gh.cmd("log", "--format=%B", pr.MergeCommitSha+"^1.."+pr.MergeCommitSha)
+			msg += "\n" + out
+		}
+		pr.Backport = gh.detectBackport(msg)
+	}
+	for _, c := range s.DirectCommits {
+		out, _ := gh.cmd("show", "-s", "--format=%B", c.SHA)
+		c.Backport = gh.detectBackport(out)
+	}
+}
+
+func (gh *ghch) detectBackport(msg string) *Backport {
+	bp := parseBackport(msg)
+	if bp == nil {
+		return nil
+	}
+	sha := bp.Commit
+	if bp.PullRequest == 0 {
+		bp.PullRequest = gh.prNumOfCommit(sha)
+	} else if sha == "" {
+		sha = gh.commitOfPR(bp.PullRequest)
+	}
+	if sha != "" {
+		bp.Release = gh.firstReleaseContaining(sha)
+	}
+	return bp
+}
+
+// prNumOfCommit finds the pull request which brought the commit into a branch
+func (gh *ghch) prNumOfCommit(sha string) int {
+	out, _ := gh.cmd("show", "-s", "--format=%s", sha)
+	if nums := parsePRRefs(out); len(nums) > 0 {
+		return nums[0]
+	}
+	out, _ = gh.cmd("log", "--ancestry-path", "--merges", "--reverse", "--format=%s", "^"+sha, "--branches", "--remotes")
+	for _, line := range strings.Split(out, "\n") {
+		if matches := prMergeSubjectReg.FindStringSubmatch(line); len(matches) > 1 {
+			i, _ := strconv.Atoi(matches[1])
+			return i
+		}
+	}
+	return 0
+}
+
+// commitOfPR finds the merge or squash commit of the pull request
+func (gh *ghch) commitOfPR(num int) string {
+	n := strconv.Itoa(num)
+	out, _ := gh.cmd("log", "--branches", "--remotes", "-n", "1", "--format=%H", "-E",
+		"--grep", `^Merge pull request #`+n+` from|\(#`+n+`\)$`)
+	return strings.TrimSpace(out)
+}
+
+func (gh *ghch) firstReleaseContaining(sha string) string {
+	out, err := gh.cmd("tag", "--contains", sha)
+	if err != nil {
+		return ""
+	}
+	tags := make(map[string]bool)
+	for _, t := range strings.Fields(out) {
+		tags[t] = true
+	}
+	vers := gh.versions()
+	for i := len(vers) - 1; i >= 0; i-- {
+		if tags[vers[i]] {
+			return vers[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package ghch
+
+import "time"
+
+// badge is the JSON schema of shields.io endpoint badges.
+// cf. https://shields.io/endpoint
+type badge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+func newBadge(ver string, releasedAt time.Time) badge {
+	b := badge{
+		SchemaVersion: 1,
+		Label:         "release",
+		Message:       "unreleased",
+		Color:         "lightgrey",
+	}
+	if ver != "" {
+		b.Message = ver + " (" + releasedAt.Format("2006-01-02") + ")"
+		b.Color = "blue"
+	}
+	return b
+}
+
+func (gh *ghch) getBadge(nextVersion string) badge {
+	if nextVersion != "" {
+		t, _ := gh.getChangedAt("")
+		return newBadge(nextVersion, t)
+	}
+	ver := gh.getLatestSemverTag()
+	if ver == "" {
+		return newBadge("", time.Time{})
+	}
+	t, _ := gh.getChangedAt(ver)
+	return newBadge(ver, t)
+}
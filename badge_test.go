@@ -0,0 +1,23 @@
+package ghch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewBadge(t *testing.T) {
+	testCases := []struct {
+		ver        string
+		releasedAt time.Time
+		expect     badge
+	}{
+		{"v0.30.2", time.Date(2016, 4, 21, 23, 0, 0, 0, time.UTC), badge{1, "release", "v0.30.2 (2016-04-21)", "blue"}},
+		{"", time.Time{}, badge{1, "release", "unreleased", "lightgrey"}},
+	}
+	for _, tc := range testCases {
+		if got := newBadge(tc.ver, tc.releasedAt); !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("newBadge(%q): got %+v, expect %+v", tc.ver, got, tc.expect)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package ghch
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// tagRef is a tag peeled to its commit
+type tagRef struct {
+	SHA       string
+	ChangedAt time.Time
+}
+
+// tagRefs lists all tags with their commits and dates by a single git call
+func (gh *ghch) tagRefs() map[string]tagRef {
+	gh.tagsOnce.Do(func() {
+		out, err := gh.cmd("for-each-ref", "refs/tags",
+			"--format=%(refname:short)%00%(objectname)%00%(*objectname)%00%(committerdate:unix)%00%(*committerdate:unix)")
+		if err != nil {
+			gh.fail(errors.Wrap(err, "failed to list tags. `git for-each-ref` failed"))
+			return
+		}
+		gh.tags = parseTagRefs(out)
+	})
+	return gh.tags
+}
+
+func parseTagRefs(out string) map[string]tagRef {
+	tags := make(map[string]tagRef)
+	for _, line := range splitLines(out) {
+		fields := strings.Split(line, "\x00")
+		if len(fields) < 5 {
+			continue
+		}
+		// fields of annotated tags are peeled to the tagged commit
+		sha, ct := fields[1], fields[3]
+		if fields[2] != "" {
+			sha, ct = fields[2], fields[4]
+		}
+		i, err := strconv.ParseInt(ct, 10, 64)
+		if err != nil {
+			continue
+		}
+		tags[fields[0]] = tagRef{SHA: sha, ChangedAt: time.Unix(i, 0)}
+	}
+	return tags
+}
+
+// historyCommit is a commit of the range-wide log
+type historyCommit struct {
+	parents []string
+	nums    []int
+}
+
+// sectionPRNums lists the pull request numbers of the sections between revs,
+// which are versions ordered newest first ending with "" for the head. The
+// whole history is read by one `git log` and each commit is attributed to the
+// oldest section containing it, instead of running `git log` per section.
+func (gh *ghch) sectionPRNums(revs []string) [][]int {
+	if gh.compare {
+		nums := make([][]int, len(revs))
+		prevRev := ""
+		for i, rev := range revs {
+			nums[i] = gh.mergedPRNums(rev, prevRev)
+			prevRev = rev
+		}
+		dedupeNums(nums)
+		return nums
+	}
+	format := "%x1e%H%x00%P%x00%s"
+	if gh.scanRefs {
+		format += "%x00%B"
+	}
+	out, err := gh.cmd("log", "--format="+format, gh.head(), "--tags")
+	if err != nil {
+		gh.fail(errors.Wrap(err, "failed to list merged pull requests. `git log` failed"))
+		return make([][]int, len(revs))
+	}
+	order, commits := parseHistory(out, gh.scanRefs, gh.offline)
+
+	tags := gh.tagRefs()
+	tips := make([]string, len(revs))
+	for i := range revs {
+		to := gh.head()
+		if i > 0 {
+			to = gh.localRevision(revs[i-1])
+		}
+		if t, ok := tags[to]; ok {
+			tips[i] = t.SHA
+		} else if sha, err := gh.cmd("rev-parse", to+"^{commit}"); err == nil {
+			tips[i] = strings.TrimSpace(sha)
+		}
+	}
+	gh.prCommits = make(map[int]string)
+	for _, sha := range order {
+		for _, num := range commits[sha].nums {
+			if _, ok := gh.prCommits[num]; !ok {
+				gh.prCommits[num] = sha
+			}
+		}
+	}
+	nums := attributeSections(order, commits, tips)
+	dedupeNums(nums)
+	return nums
+}
+
+// parseHistory reads `git log` of "%x1e%H%x00%P%x00%s", plus "%x00%B" with
+// scanRefs. Subjects of squash merges are taken with squashes.
+func parseHistory(out string, scanRefs, squashes bool) (order []string, commits map[string]*historyCommit) {
+	commits = make(map[string]*historyCommit)
+	for _, rec := range strings.Split(normalizeNewlines(out), "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(rec), "\x00", 4)
+		if len(fields) < 3 {
+			continue
+		}
+		c := &historyCommit{parents: strings.Fields(fields[1])}
+		if len(c.parents) > 1 {
+			if matches := prMergeSubjectReg.FindStringSubmatch(fields[2]); len(matches) > 1 {
+				i, _ := strconv.Atoi(matches[1])
+				c.nums = append(c.nums, i)
+			}
+		} else if squashes {
+			c.nums = parseSquashSubject(fields[2])
+		}
+		if scanRefs && len(fields) > 3 {
+			c.nums = appendUniqueNums(c.nums, parsePRRefs(fields[3])...)
+		}
+		order = append(order, fields[0])
+		commits[fields[0]] = c
+	}
+	return
+}
+
+// attributeSections walks the history from the tip of the oldest section to
+// the newest one, so that each commit belongs to the first tip reaching it
+func attributeSections(order []string, commits map[string]*historyCommit, tips []string) [][]int {
+	owner := make(map[string]int)
+	for i := len(tips) - 1; i >= 0; i-- {
+		stack := []string{tips[i]}
+		for len(stack) > 0 {
+			sha := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			c, ok := commits[sha]
+			if _, seen := owner[sha]; seen || !ok {
+				continue
+			}
+			owner[sha] = i
+			stack = append(stack, c.parents...)
+		}
+	}
+	nums := make([][]int, len(tips))
+	for _, sha := range order {
+		if i, ok := owner[sha]; ok {
+			nums[i] = append(nums[i], commits[sha].nums...)
+		}
+	}
+	return nums
+}
@@ -0,0 +1,38 @@
+package ghch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTagRefs(t *testing.T) {
+	out := "v0.1.0\x00aaa\x00\x001461750000\x00\n" +
+		"v0.2.0\x00ttt\x00bbb\x001461760000\x001461755000\n"
+	expect := map[string]tagRef{
+		"v0.1.0": {SHA: "aaa", ChangedAt: time.Unix(1461750000, 0)},
+		"v0.2.0": {SHA: "bbb", ChangedAt: time.Unix(1461755000, 0)},
+	}
+	if got := parseTagRefs(out); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %+v, expect %+v", got, expect)
+	}
+}
+
+func TestAttributeSections(t *testing.T) {
+	// m4 merges the maintenance branch of v0.1.1 which is not contained in v0.2.0
+	out := "\x1em4\x00m2 m3\x00Merge pull request #4 from foo/rel" +
+		"\x1em3\x00m1 c3\x00Merge pull request #3 from foo/fix" +
+		"\x1ec3\x00m1\x00fix" +
+		"\x1em2\x00m1 c2\x00Merge pull request #2 from foo/feature" +
+		"\x1ec2\x00m1\x00feature (#5)" +
+		"\x1em1\x00root c1\x00Merge pull request #1 from foo/init" +
+		"\x1ec1\x00root\x00init" +
+		"\x1eroot\x00\x00root"
+	order, commits := parseHistory(out, false, false)
+	// tips of sections HEAD, v0.2.0, v0.1.1 and v0.1.0
+	got := attributeSections(order, commits, []string{"m4", "m2", "m3", "m1"})
+	expect := [][]int{{4}, {2}, {3}, {1}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+}
@@ -0,0 +1,240 @@
+package ghch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/octokit/go-octokit/octokit"
+	"github.com/pkg/errors"
+)
+
+// cachedPR is a merged pull request stored in the cache directory. Merged
+// pull requests hardly change, so the entry is kept as long as the history
+// has the same merge commit.
+type cachedPR struct {
+	PullRequest *octokit.PullRequest `json:"pull_request"`
+	// null when they were not fetched
+	Labels []string `json:"labels"`
+	Files  []string `json:"files"`
+}
+
+// cachePath returns the file of the pull request merged by sha
+func (gh *ghch) cachePath(owner, repo, sha string) string {
+	return filepath.Join(gh.cacheDir, owner, repo, "pulls", sha+".json")
+}
+
+func (gh *ghch) loadCachedPR(owner, repo string, num int) *PullRequest {
+	sha, ok := gh.prCommits[num]
+	if gh.cacheDir == "" || !ok {
+		return nil
+	}
+	b, err := ioutil.ReadFile(gh.cachePath(owner, repo, sha))
+	var c cachedPR
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.PullRequest == nil || c.PullRequest.Number != num {
+		gh.apiStats.lookedUpPR(false)
+		return nil
+	}
+	gh.apiStats.lookedUpPR(true)
+	return &PullRequest{PullRequest: c.PullRequest, Labels: c.Labels, Files: c.Files}
+}
+
+func (gh *ghch) storeCachedPR(owner, repo string, pr *PullRequest) {
+	sha, ok := gh.prCommits[pr.Number]
+	if gh.cacheDir == "" || !ok {
+		return
+	}
+	if err := writeJSONFile(gh.cachePath(owner, repo, sha), cachedPR{
+		PullRequest: pr.PullRequest,
+		Labels:      pr.Labels,
+		Files:       pr.Files,
+	}); err != nil {
+		// the cache is only an optimization
+		log.Print(errors.Wrapf(err, "failed to cache #%d", pr.Number))
+	}
+}
+
+func writeJSONFile(file string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first not to leave a broken entry behind
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// sectionCacheVersion is bumped when cached sections become incompatible
+const sectionCacheVersion = 2
+
+// cachedSection is a whole released section stored in the cache directory.
+// Categories and highlights refer to its pull requests by index, to keep
+// them shared after decoding.
+type cachedSection struct {
+	Section    Section          `json:"section"`
+	Categories []cachedCategory `json:"categories"`
+	Highlights []int            `json:"highlights"`
+}
+
+type cachedCategory struct {
+	Name         string   `json:"name"`
+	PullRequests []int    `json:"pull_requests"`
+	Extras       []*Extra `json:"extras"`
+}
+
+func newCachedSection(s Section) cachedSection {
+	idx := make(map[*PullRequest]int, len(s.PullRequests))
+	for i, pr := range s.PullRequests {
+		idx[pr] = i
+	}
+	indexes := func(prs []*PullRequest) []int {
+		var ret []int
+		for _, pr := range prs {
+			if i, ok := idx[pr]; ok {
+				ret = append(ret, i)
+			}
+		}
+		return ret
+	}
+	c := cachedSection{Section: s, Highlights: indexes(s.Highlights)}
+	for _, cat := range s.Categories {
+		c.Categories = append(c.Categories, cachedCategory{
+			Name:         cat.Name,
+			PullRequests: indexes(cat.PullRequests),
+			Extras:       cat.Extras,
+		})
+	}
+	return c
+}
+
+func (c cachedSection) section() Section {
+	s := c.Section
+	prs := func(indexes []int) []*PullRequest {
+		var ret []*PullRequest
+		for _, i := range indexes {
+			if i >= 0 && i < len(s.PullRequests) {
+				ret = append(ret, s.PullRequests[i])
+			}
+		}
+		return ret
+	}
+	s.Highlights = prs(c.Highlights)
+	for _, cc := range c.Categories {
+		s.Categories = append(s.Categories, &Category{
+			Name:         cc.Name,
+			PullRequests: prs(cc.PullRequests),
+			Extras:       cc.Extras,
+		})
+	}
+	return s
+}
+
+// sectionCacheKey identifies a released section by the names and commits of
+// both ends and everything else changing its content, or returns "" when it
+// should not be cached. Names tell tags on the same commit apart. The pull
+// requests picked up of the range are part of it, as they depend on how the
+// range was scanned. Reactions are left out not to get stale, and sections
+// built offline not to be served once the API is available.
+func (gh *ghch) sectionCacheKey(from, to string, nums []int) string {
+	if gh.cacheDir == "" || to == "" || gh.offline || gh.reactions || gh.config.needsReactions() {
+		return ""
+	}
+	toSHA := gh.commitOf(gh.localRevision(to))
+	if toSHA == "" {
+		return ""
+	}
+	var fromSHA string
+	if from != "" {
+		if fromSHA = gh.commitOf(gh.localRevision(from)); fromSHA == "" {
+			return ""
+		}
+	}
+	extras, err := gh.extrasOf(to)
+	if err != nil {
+		return ""
+	}
+	b, err := json.Marshal(struct {
+		Version       int
+		From, To      string
+		FromSHA       string
+		ToSHA         string
+		Config        *config
+		Extras        []*Extra
+		DirectCommits bool
+		Backports     bool
+		Stats         bool
+		Compare       bool
+		Verbose       bool
+		Sizes         bool
+		Reverts       string
+		VersionsFrom  string
+		GitHubNotes   string
+		WebURL        string
+		ExcludePRs    map[int]bool
+		ScanRefs      bool
+		VerifyRefs    bool
+		PullRequests  []int
+	}{
+		sectionCacheVersion, from, to, fromSHA, toSHA, gh.config, extras,
+		gh.directCommits, gh.backports, gh.stats, gh.compare, gh.verbose, gh.sizes,
+		gh.reverts, gh.versionsFrom, gh.githubNotes, gh.getWebURL(), gh.excludePRs,
+		gh.scanRefs, gh.verifyRefs, sortedNums(nums),
+	})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (gh *ghch) sectionCachePath(key string) string {
+	owner, repo := gh.ownerAndRepo()
+	return filepath.Join(gh.cacheDir, owner, repo, "sections", key+".json")
+}
+
+func (gh *ghch) loadCachedSection(key string) (Section, bool) {
+	if key == "" {
+		return Section{}, false
+	}
+	b, err := ioutil.ReadFile(gh.sectionCachePath(key))
+	var c cachedSection
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	gh.apiStats.lookedUpSection(err == nil)
+	if err != nil {
+		return Section{}, false
+	}
+	s := c.section()
+	s.WebURL = gh.getWebURL()
+	return s, true
+}
+
+func (gh *ghch) storeCachedSection(key string, s Section) {
+	if key == "" {
+		return
+	}
+	if err := writeJSONFile(gh.sectionCachePath(key), newCachedSection(s)); err != nil {
+		log.Print(errors.Wrapf(err, "failed to cache section %s", s.ToRevision))
+	}
+}
+
+func sortedNums(nums []int) []int {
+	sorted := append([]int{}, nums...)
+	sort.Ints(sorted)
+	return sorted
+}
@@ -0,0 +1,153 @@
+package ghch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Songmu/ghch/ghchtest"
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestPRCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghch-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gh := &ghch{cacheDir: dir, prCommits: map[int]string{12: "0123abc"}}
+	if pr := gh.loadCachedPR("Songmu", "ghch", 12); pr != nil {
+		t.Fatalf("empty cache should miss: %+v", pr)
+	}
+	pr := &PullRequest{
+		PullRequest: &octokit.PullRequest{Number: 12, Title: "Add cache"},
+		Labels:      []string{},
+		Files:       []string{"cache.go"},
+	}
+	gh.storeCachedPR("Songmu", "ghch", pr)
+
+	got := gh.loadCachedPR("Songmu", "ghch", 12)
+	if got == nil {
+		t.Fatal("stored pull request should hit")
+	}
+	if got.Title != "Add cache" || !reflect.DeepEqual(got.Files, pr.Files) {
+		t.Errorf("got %+v", got)
+	}
+	if got.Labels == nil {
+		t.Error("fetched empty labels should be kept apart from unfetched ones")
+	}
+
+	// the merge commit changed, such as by a rewritten history
+	gh.prCommits[12] = "4567def"
+	if pr := gh.loadCachedPR("Songmu", "ghch", 12); pr != nil {
+		t.Errorf("cache of another merge commit should miss: %+v", pr)
+	}
+}
+
+func TestCachedSection(t *testing.T) {
+	fix := &PullRequest{PullRequest: &octokit.PullRequest{Number: 1, Title: "Fix a bug"}, Highlight: true}
+	feat := &PullRequest{PullRequest: &octokit.PullRequest{Number: 2, Title: "Add a feature"}}
+	note := &Extra{Title: "Moved to a new home"}
+	s := Section{
+		PullRequests: []*PullRequest{fix, feat},
+		Categories: []*Category{
+			{Name: "Bug Fixes", PullRequests: []*PullRequest{fix}},
+			{Name: "Other Changes", PullRequests: []*PullRequest{feat}, Extras: []*Extra{note}},
+		},
+		Highlights: []*PullRequest{fix},
+		ToRevision: "v0.2.0",
+	}
+	b, err := json.Marshal(newCachedSection(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c cachedSection
+	if err := json.Unmarshal(b, &c); err != nil {
+		t.Fatal(err)
+	}
+	got := c.section()
+	if len(got.PullRequests) != 2 || got.ToRevision != "v0.2.0" {
+		t.Fatalf("got %+v", got)
+	}
+	if len(got.Categories) != 2 || got.Categories[0].PullRequests[0] != got.PullRequests[0] {
+		t.Errorf("categories should share pull requests of the section: %+v", got.Categories)
+	}
+	if len(got.Highlights) != 1 || got.Highlights[0] != got.PullRequests[0] {
+		t.Errorf("highlights should share pull requests of the section: %+v", got.Highlights)
+	}
+	if e := got.Categories[1].Extras; len(e) != 1 || e[0].Title != note.Title {
+		t.Errorf("extras of categories should be kept: %+v", e)
+	}
+}
+
+func TestSectionCacheKey(t *testing.T) {
+	gh := &ghch{config: &config{}}
+	if key := gh.sectionCacheKey("v0.1.0", "v0.2.0", nil); key != "" {
+		t.Errorf("sections should not be cached without --cache-dir: %s", key)
+	}
+	gh.cacheDir = "cache"
+	if key := gh.sectionCacheKey("v0.2.0", "", nil); key != "" {
+		t.Errorf("unreleased sections should not be cached: %s", key)
+	}
+}
+
+func TestSectionCacheKeyOfPRs(t *testing.T) {
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	repo.MergePR(1, "alice", "Add a feature")
+	repo.Tag("v0.1.0")
+	repo.SquashPR(2, "bob", "Fix a crash")
+	repo.Tag("v0.2.0")
+	gh := (&ghch{repoPath: repo.Dir, gitPath: "git", cacheDir: "cache", config: &config{}}).initialize()
+
+	key := gh.sectionCacheKey("v0.1.0", "v0.2.0", nil)
+	if key == "" {
+		t.Fatal("released sections should be cached")
+	}
+	if got := gh.sectionCacheKey("v0.1.0", "v0.2.0", []int{2}); got == key {
+		t.Error("sections of other pull requests should have other keys")
+	}
+	if gh.sectionCacheKey("v0.1.0", "v0.2.0", []int{3, 2}) != gh.sectionCacheKey("v0.1.0", "v0.2.0", []int{2, 3}) {
+		t.Error("keys should not depend on the order of pull requests")
+	}
+	repo.Git("tag", "v0.2.0-rc.1", "v0.2.0^{commit}")
+	if got := gh.sectionCacheKey("v0.1.0", "v0.2.0-rc.1", nil); got == key {
+		t.Error("tags on the same commit should have other keys")
+	}
+	gh.scanRefs = true
+	if got := gh.sectionCacheKey("v0.1.0", "v0.2.0", nil); got == key {
+		t.Error("sections scanned with --refs should have other keys")
+	}
+}
+
+func TestPRCacheOfRange(t *testing.T) {
+	srv := ghchtest.NewServer()
+	defer srv.Close()
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	repo.Tag("v0.1.0")
+	srv.AddPullRequest("Songmu", "ghch", repo.MergePR(1, "alice", "Add a feature"))
+	srv.AddPullRequest("Songmu", "ghch", repo.SquashPR(2, "bob", "Fix a crash"))
+	repo.Tag("v0.2.0")
+	dir, err := ioutil.TempDir("", "ghch-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 2; i++ {
+		gh := (&ghch{repoPath: repo.Dir, gitPath: "git", apiURL: srv.APIURL(), cacheDir: dir, scanRefs: true, config: &config{}}).initialize()
+		nums := gh.mergedPRNums("v0.1.0", "v0.2.0")
+		if !reflect.DeepEqual(nums, []int{1, 2}) {
+			t.Fatalf("got %v, expect [1 2]", nums)
+		}
+		if prs := gh.pullRequests(nums, true); len(prs) != 2 {
+			t.Fatalf("got %d pull requests, expect 2", len(prs))
+		}
+	}
+	// the second run is served from the cache
+	if got := srv.Requests(); len(got) != 2 {
+		t.Errorf("got requests %v, expect the two of the first run", got)
+	}
+}
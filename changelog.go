@@ -0,0 +1,138 @@
+package ghch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const defaultChangelogHeader = "# Changelog"
+
+// writeChangelog writes rendered markdown into the changelog file. A single
+// section is inserted above the existing ones, while a whole changelog
+// (--all) replaces them, keeping the preamble of the file.
+func (cli *CLI) writeChangelog(gh *ghch, rdr *renderer, chlog Changelog, opts *ghOpts) error {
+	path := gh.repoFile(opts.Changelog)
+	orig, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to read changelog")
+	}
+	if !opts.All {
+		// don't list pull requests again which are already in the changelog
+		listed := listedPRNums(string(orig))
+		for i := range chlog.Sections {
+			chlog.Sections[i].removePRs(func(pr *PullRequest) bool {
+				return listed[pr.Number]
+			})
+		}
+	}
+
+	str, err := rdr.changelog(chlog)
+	if err != nil {
+		return err
+	}
+	if opts.Edit {
+		if str, err = editText(str); err != nil {
+			return err
+		}
+	}
+	// keep CRLF of changelogs checked out on Windows
+	crlf := strings.Contains(string(orig), "\r\n")
+	updated := updateChangelog(normalizeNewlines(string(orig)), str, opts.All)
+	if crlf {
+		updated = strings.Replace(updated, "\n", "\r\n", -1)
+	}
+
+	if opts.DryRun {
+		diff, err := gh.diff(path, string(orig), updated)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cli.OutStream, diff)
+		return nil
+	}
+	if err := ioutil.WriteFile(path, []byte(updated), 0644); err != nil {
+		return errors.Wrap(err, "failed to write changelog")
+	}
+	return nil
+}
+
+var prLinkReg = regexp.MustCompile(`/pull/([0-9]+)\)`)
+
+// listedPRNums collects pull requests linked from a changelog
+func listedPRNums(str string) map[int]bool {
+	nums := make(map[int]bool)
+	for _, matches := range prLinkReg.FindAllStringSubmatch(str, -1) {
+		i, _ := strconv.Atoi(matches[1])
+		nums[i] = true
+	}
+	return nums
+}
+
+func updateChangelog(orig, rendered string, replace bool) string {
+	preamble, sections := splitChangelog(orig)
+	if preamble == "" {
+		preamble = defaultChangelogHeader
+	}
+	if strings.HasPrefix(rendered, "# ") {
+		// the header template rendered its own preamble
+		preamble = ""
+	}
+	body := rendered
+	if !replace && sections != "" {
+		body += "\n\n" + sections
+	}
+	if preamble != "" {
+		body = preamble + "\n\n" + body
+	}
+	return strings.TrimRight(body, "\n") + "\n"
+}
+
+// splitChangelog splits a changelog into the text before the first section
+// heading and the sections
+func splitChangelog(str string) (preamble, sections string) {
+	if strings.HasPrefix(str, "## ") {
+		return "", strings.TrimSpace(str)
+	}
+	if i := strings.Index(str, "\n## "); i >= 0 {
+		return strings.TrimSpace(str[:i]), strings.TrimSpace(str[i+1:])
+	}
+	return strings.TrimSpace(str), ""
+}
+
+// diff shows a unified diff of the file contents using `git diff --no-index`
+func (gh *ghch) diff(path, before, after string) (string, error) {
+	dir, err := ioutil.TempDir("", "ghch-diff")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create diff")
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Base(path)
+	a, b := filepath.Join("a", name), filepath.Join("b", name)
+	for p, content := range map[string]string{a: before, b: after} {
+		p = filepath.Join(dir, p)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			return "", errors.Wrap(err, "failed to create diff")
+		}
+	}
+	cmd := exec.Command(gh.gitProg(), "diff", "--no-index", "--no-color", "--src-prefix=", "--dst-prefix=", "--", a, b)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		// exit status 1 just means there were differences
+		err = nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create diff. `git diff` failed")
+	}
+	return string(out), nil
+}
@@ -0,0 +1,133 @@
+package ghch
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestUpdateChangelog(t *testing.T) {
+	orig := `# Changelog
+
+Notable changes of the project.
+
+## [v0.0.1](https://github.com/Songmu/ghch/releases/tag/v0.0.1) (2016-05-04)
+
+* original version [#1](https://github.com/Songmu/ghch/pull/1) ([Songmu](https://github.com/Songmu))
+`
+	section := `## [v0.0.2](https://github.com/Songmu/ghch/releases/tag/v0.0.2) (2016-05-10)
+
+* add --all [#2](https://github.com/Songmu/ghch/pull/2) ([Songmu](https://github.com/Songmu))`
+
+	testCases := []struct {
+		name     string
+		orig     string
+		rendered string
+		replace  bool
+		expect   string
+	}{
+		{
+			name:     "insert section",
+			orig:     orig,
+			rendered: section,
+			expect: `# Changelog
+
+Notable changes of the project.
+
+` + section + `
+
+## [v0.0.1](https://github.com/Songmu/ghch/releases/tag/v0.0.1) (2016-05-04)
+
+* original version [#1](https://github.com/Songmu/ghch/pull/1) ([Songmu](https://github.com/Songmu))
+`,
+		},
+		{
+			name:     "replace sections",
+			orig:     orig,
+			rendered: section,
+			replace:  true,
+			expect:   "# Changelog\n\nNotable changes of the project.\n\n" + section + "\n",
+		},
+		{
+			name:     "new file",
+			rendered: section,
+			expect:   "# Changelog\n\n" + section + "\n",
+		},
+		{
+			name:     "header rendered by template",
+			orig:     orig,
+			rendered: "# Releases\n\n" + section,
+			replace:  true,
+			expect:   "# Releases\n\n" + section + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		if got := updateChangelog(tc.orig, tc.rendered, tc.replace); got != tc.expect {
+			t.Errorf("%s: got\n%s\nexpect\n%s", tc.name, got, tc.expect)
+		}
+	}
+}
+
+func TestDedupeNums(t *testing.T) {
+	nums := [][]int{{5, 3, 4}, {3, 2}, {2, 1}}
+	dedupeNums(nums)
+
+	expect := [][]int{{5, 4}, {3}, {2, 1}}
+	if !reflect.DeepEqual(nums, expect) {
+		t.Errorf("got %v, expect %v", nums, expect)
+	}
+}
+
+func TestRemovePRs(t *testing.T) {
+	pr := func(num int) *PullRequest {
+		return &PullRequest{PullRequest: &octokit.PullRequest{Number: num}}
+	}
+	prs := []*PullRequest{pr(5), pr(3), pr(4)}
+	s := Section{PullRequests: prs, Categories: []*Category{{Name: "Fixes", PullRequests: prs[1:2]}, {Name: "Features", PullRequests: []*PullRequest{prs[0], prs[2]}}}}
+	s.removePRs(func(pr *PullRequest) bool { return pr.Number == 3 })
+
+	if len(s.PullRequests) != 2 {
+		t.Errorf("got %d pull requests, expect 2", len(s.PullRequests))
+	}
+	if cats := s.Categories; len(cats) != 1 || cats[0].Name != "Features" {
+		t.Errorf("emptied categories should be dropped: %+v", cats)
+	}
+}
+
+func TestListedPRNums(t *testing.T) {
+	str := `## [v0.0.2](https://github.com/Songmu/ghch/releases/tag/v0.0.2) (2016-05-10)
+
+* add --all [#2](https://github.com/Songmu/ghch/pull/2) ([Songmu](https://github.com/Songmu))
+* original version [#1](https://github.com/Songmu/ghch/pull/1) ([Songmu](https://github.com/Songmu))
+`
+	expect := map[int]bool{1: true, 2: true}
+	if got := listedPRNums(str); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+}
+
+func TestStreamChangelogJSON(t *testing.T) {
+	chlog := Changelog{Sections: []Section{
+		{ToRevision: "v0.2.0", FromRevision: "v0.1.0", PullRequests: []*PullRequest{}},
+		{ToRevision: "v0.1.0", PullRequests: []*PullRequest{}},
+	}}
+	var b bytes.Buffer
+	err := streamChangelogJSON(&b, func(fn func(Section) error) error {
+		for _, s := range chlog.Sections {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, _ := json.MarshalIndent(chlog, "", "  ")
+	if b.String() != string(expect)+"\n" {
+		t.Errorf("got\n%s\nexpect\n%s", b.String(), expect)
+	}
+}
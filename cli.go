@@ -1,30 +1,77 @@
 package ghch
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
-	"strings"
-	"text/template"
+	"sort"
 	"time"
 
 	"github.com/jessevdk/go-flags"
-	"github.com/octokit/go-octokit/octokit"
+	"github.com/pkg/errors"
 )
 
 type ghOpts struct {
 	RepoPath    string `short:"r" long:"repo" default:"." description:"git repository path"`
 	GitPath     string `short:"g" long:"git" default:"git" description:"git path"`
+	GitDir      string `          long:"git-dir" description:"git directory of a bare repository or a worktree (default: $GIT_DIR)"`
+	WorkTree    string `          long:"work-tree" description:"working tree of the --git-dir (default: $GIT_WORK_TREE)"`
 	From        string `short:"f" long:"from" description:"git commit revision range start from"`
 	To          string `short:"t" long:"to" description:"git commit revision range end to"`
 	Token       string `          long:"token" description:"github token"`
+	APIURL      string `          long:"api-url" description:"GitHub API root URL (default: https://api.github.com)"`
+	WebURL      string `          long:"web-url" description:"root URL of links in the output (default: https://github.com)"`
 	Verbose     bool   `short:"v" long:"verbose"`
+	Quiet       bool   `short:"q" long:"quiet" description:"suppress all output, implies --exit-code"`
+	ExitCode    bool   `          long:"exit-code" description:"exit with 3 when no changes are found"`
 	Remote      string `          long:"remote" default:"origin" description:"default remote name"`
-	Format      string `short:"F" long:"format" default:"json" description:"json or markdown"`
+	Branch      string `short:"b" long:"branch" description:"generate changelog of the branch, using only tags reachable from it"`
+	Format      string `short:"F" long:"format" default:"json" description:"json, jsonl, markdown, html, provenance-json, badge, numbers or numbers-json, or comma separated ones with --output-dir"`
 	All         bool   `short:"A" long:"all" description:"output all changes"`
 	NextVersion string `short:"N" long:"next-version"`
+	TemplateDir string `          long:"template-dir" description:"directory of *.tmpl files overriding the markdown templates"`
+	FrontMatter string `          long:"front-matter" description:"prefix markdown with YAML front matter per \"section\" or for the whole \"document\""`
+	ScanRefs    bool   `          long:"refs" description:"also pick up PR references like (#123) anywhere in commit messages"`
+	VerifyRefs  bool   `          long:"verify-refs" description:"confirm via the API that picked up PRs are merged"`
+	Direct      bool   `          long:"direct-commits" description:"also list commits pushed without a pull request"`
+	Backports   bool   `          long:"backports" description:"annotate cherry-picked and backported changes with their origin"`
+	Write       bool   `short:"w" long:"write" description:"write markdown into the changelog file instead of printing"`
+	Changelog   string `          long:"changelog" default:"CHANGELOG.md" description:"changelog file to write, relative to the repository"`
+	DryRun      bool   `short:"n" long:"dry-run" description:"print what would be written or created without doing it"`
+	Edit        bool   `short:"e" long:"edit" description:"edit the notes with $EDITOR before writing or creating a release"`
+	Config      string `short:"c" long:"config" description:"config file (default: .ghch.yml in the repository)"`
+	Unshallow   bool   `          long:"unshallow" description:"fetch the full history when the repository is a shallow clone"`
+	Fetch       bool   `          long:"fetch" description:"fetch tags and the branch from the remote before generating"`
+	Stats       bool   `          long:"stats" description:"add metrics such as counts and lead time to each section"`
+	Compare     bool   `          long:"compare" description:"list commits between revisions with the GitHub compare API instead of git log"`
+	CacheDir    string `          long:"cache-dir" description:"directory to cache pull requests and sections of released versions, keyed by their commits"`
+	TitleEscape string `          long:"escape-titles" default:"none" description:"escape titles in markdown for \"html\", \"markdown\" or \"none\""`
+	NoMentions  bool   `          long:"escape-mentions" description:"wrap @mentions in titles in code spans not to notify anyone"`
+	NoAutolinks bool   `          long:"escape-refs" description:"wrap #123 references in titles in code spans not to be linked"`
+	GroupBy     string `          long:"group-by" description:"group sections of --all output under \"year\" or \"month\" headings in markdown"`
+	Limit       int    `          long:"limit" description:"output only the N most recent sections, implies --all"`
+	Reverts     string `          long:"reverts" description:"\"drop\" or \"annotate\" changes reverted in the same section along with their reverts"`
+	Milestone   string `          long:"milestone" description:"list merged pull requests of the GitHub milestone instead of those between tags"`
+	VersionsOf  string `          long:"versions-from" default:"tags" description:"take versions from semver \"tags\" or published GitHub \"releases\""`
+	Scheme      string `          long:"version-scheme" default:"semver" description:"versions among tags: \"semver\", \"calver\" like 2024.06.1, or \"regex:<pattern>\" ordered by its groups"`
+	GitHubNotes string `          long:"github-notes" description:"\"merge\" new contributors and pull requests of the notes GitHub generates, or \"diff\" them"`
+	Sizes       bool   `          long:"sizes" description:"annotate pull requests with their additions, deletions, changed files and size"`
+	OutputDir   string `          long:"output-dir" description:"write each of the formats into CHANGELOG.md, changelog.json or changelog.html in the directory"`
+	Collapse    int    `          long:"collapse" description:"wrap categories of more than N pull requests in <details> in markdown"`
+	TOC         bool   `          long:"toc" description:"prefix markdown of --all with the index of versions linking to their headings"`
+	ExcludePRs  []int  `          long:"exclude-pr" description:"leave out the pull request of the number, repeatable"`
+	Reactions   bool   `          long:"reactions" description:"fetch 👍 reactions and comments of pull requests"`
+	MaxPRs      int    `          long:"max-prs" description:"refuse or confirm ranges of more pull requests than this, 0 for no limit"`
+	Upstream    bool   `          long:"upstream" description:"resolve the repository of --remote to its parent by the API when it is a fork"`
+	Offline     bool   `          long:"offline" description:"build pull requests from merge and squash commits without the GitHub API"`
+	APIStats    bool   `          long:"api-stats" description:"log API calls, cache hits and the remaining rate limit at the end, also with --verbose"`
+
+	Vars []string `long:"var" description:"key=value given to templates as {{.Vars.key}}, repeatable"`
+
+	// set by subcommands whose --edit works without --write, like release
+	editsOutput bool
 	// Tmpl string
 }
 
@@ -32,6 +79,10 @@ const (
 	exitCodeOK = iota
 	exitCodeParseFlagError
 	exitCodeErr
+	exitCodeNoChanges
+	exitCodePartial
+	exitCodeFindings
+	exitCodeDiffers
 )
 
 // CLI is struct for command line tool
@@ -42,129 +93,684 @@ type CLI struct {
 // Run the ghch
 func (cli *CLI) Run(argv []string) int {
 	log.SetOutput(cli.ErrStream)
+	if len(argv) > 0 {
+		if cmd, ok := commands[argv[0]]; ok {
+			return cmd(cli, argv[1:])
+		}
+	}
+
 	p, opts, err := parseArgs(argv)
 	if err != nil {
-		if ferr, ok := err.(*flags.Error); !ok || ferr.Type != flags.ErrHelp {
-			p.WriteHelp(cli.ErrStream)
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+
+	gh, err := opts.newGhch()
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	rdr, err := opts.newRenderer(gh.config)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+
+	if formats := splitFormats(opts.Format); len(formats) > 1 || opts.OutputDir != "" {
+		if err := validOutputFormats(formats, opts.OutputDir); err != nil {
+			log.Print(err)
+			return exitCodeErr
+		}
+		if opts.Write {
+			log.Print("--output-dir can't be combined with --write")
+			return exitCodeErr
+		}
+		return cli.writeOutputs(gh, rdr, opts, formats)
+	}
+
+	if opts.Format == "badge" {
+		jsn, _ := json.MarshalIndent(gh.getBadge(opts.NextVersion), "", "  ")
+		fmt.Fprintln(cli.OutStream, string(jsn))
+		return exitCodeOK
+	}
+
+	if opts.Format == "jsonl" {
+		return cli.streamJSONL(gh, opts)
+	}
+
+	if opts.Format == "numbers" || opts.Format == "numbers-json" {
+		return cli.printNumbers(gh, opts)
+	}
+
+	if opts.GitHubNotes == githubNotesDiff {
+		return cli.diffGitHubNotes(gh, opts)
+	}
+
+	// the index and headers listing sections need all of them before the first one is printed
+	if opts.All && !opts.Write && opts.Format != "html" && opts.Format != "provenance-json" && !rdr.toc && !rdr.headerNeedsSections() {
+		return cli.streamChangelog(gh, rdr, opts)
+	}
+
+	var chlog Changelog
+	if opts.All {
+		chlog = gh.getChangelog(opts.NextVersion)
+	} else {
+		chlog = Changelog{Sections: []Section{gh.getCurrentSection(opts.From, opts.To, opts.NextVersion)}}
+	}
+
+	if opts.Write {
+		if err := cli.writeChangelog(gh, rdr, chlog, opts); err != nil {
+			log.Print(err)
+			return exitCodeErr
 		}
-		return exitCodeParseFlagError
+		return gh.exitCode(chlog.isEmpty(), opts.ExitCode || opts.Quiet)
+	}
+
+	if opts.Format == "markdown" {
+		str, err := rdr.changelog(chlog)
+		if err != nil {
+			log.Print(err)
+		} else {
+			fmt.Fprintln(cli.OutStream, str)
+		}
+	} else if opts.Format == "html" {
+		b, err := renderHTMLChangelog(chlog)
+		if err != nil {
+			log.Print(err)
+		} else {
+			cli.OutStream.Write(b)
+		}
+	} else if opts.Format == "provenance-json" {
+		jsn, _ := json.MarshalIndent(gh.provenance(chlog), "", "  ")
+		fmt.Fprintln(cli.OutStream, string(jsn))
+	} else {
+		jsn, _ := json.MarshalIndent(chlog.Sections[0], "", "  ")
+		fmt.Fprintln(cli.OutStream, string(jsn))
 	}
+	return gh.exitCode(chlog.isEmpty(), opts.ExitCode || opts.Quiet)
+}
+
+func (cli *CLI) setQuiet(quiet bool) {
+	if quiet {
+		cli.OutStream = ioutil.Discard
+		log.SetOutput(ioutil.Discard)
+	}
+}
 
+// commands are subcommands dispatched by the first argument
+var commands = map[string]func(*CLI, []string) int{
+	"backfill": (*CLI).runBackfill,
+	"digest":   (*CLI).runDigest,
+	"init":     (*CLI).runInit,
+	"lint":     (*CLI).runLint,
+	"notify":   (*CLI).runNotify,
+	"release":  (*CLI).runRelease,
+	"site":     (*CLI).runSite,
+	"stats":    (*CLI).runStats,
+	"tag":      (*CLI).runTag,
+}
+
+func (cli *CLI) parseError(p *flags.Parser, err error) int {
+	if ferr, ok := err.(*flags.Error); !ok || ferr.Type != flags.ErrHelp {
+		p.WriteHelp(cli.ErrStream)
+	}
+	return exitCodeParseFlagError
+}
+
+func parseArgs(args []string) (*flags.Parser, *ghOpts, error) {
+	opts := &ghOpts{}
+	p, _, err := parseCommandArgs("", opts, args)
+	return p, opts, err
+}
+
+func parseCommandArgs(name string, opts interface{}, args []string) (*flags.Parser, []string, error) {
+	p := flags.NewParser(opts, flags.Default)
+	p.Usage = "[OPTIONS]\n\nVersion: " + version
+	if name != "" {
+		p.Usage = name + " " + p.Usage
+	}
+	rest, err := p.ParseArgs(args)
+	return p, rest, err
+}
+
+func (opts *ghOpts) newGhch() (*ghch, error) {
+	if opts.TOC && opts.Write {
+		return nil, errors.New("--toc can't be combined with --write, which keeps the top of the changelog file")
+	}
+	if opts.Limit > 0 {
+		if opts.Write {
+			// --write replaces all sections with the output of --all
+			return nil, errors.New("--limit can't be combined with --write, which would drop older sections")
+		}
+		opts.All = true
+	}
+	if opts.Edit && !opts.Write && !opts.editsOutput {
+		return nil, errors.New("--edit needs --write, or `ghch release` or `ghch tag` to edit what they create")
+	}
+	if opts.Milestone != "" && opts.All {
+		return nil, errors.New("--milestone can't be combined with --all, which lists sections by tags")
+	}
+	if err := validVersionsFrom(opts.VersionsOf); err != nil {
+		return nil, err
+	}
+	scheme, err := parseVersionScheme(opts.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	if err := validGitHubNotes(opts.GitHubNotes); err != nil {
+		return nil, err
+	}
+	if opts.GitHubNotes == githubNotesDiff && opts.All {
+		return nil, errors.New("--github-notes=diff compares a single section and can't be combined with --all")
+	}
+	switch opts.Reverts {
+	case "", revertsDrop, revertsAnnotate:
+	default:
+		return nil, errors.Errorf("unknown --reverts %q: must be drop or annotate", opts.Reverts)
+	}
 	gh := (&ghch{
 		remote:   opts.Remote,
+		branch:   opts.Branch,
 		repoPath: opts.RepoPath,
+		gitDir:   opts.GitDir,
+		workTree: opts.WorkTree,
 		gitPath:  opts.GitPath,
 		verbose:  opts.Verbose,
 		token:    opts.Token,
+		apiURL:   opts.APIURL,
+		webURL:   opts.WebURL,
+
+		scanRefs:      opts.ScanRefs,
+		verifyRefs:    opts.VerifyRefs,
+		directCommits: opts.Direct,
+		backports:     opts.Backports,
+		stats:         opts.Stats,
+		limit:         opts.Limit,
+		compare:       opts.Compare,
+		cacheDir:      opts.CacheDir,
+		reverts:       opts.Reverts,
+		milestone:     opts.Milestone,
+		versionsFrom:  opts.VersionsOf,
+		scheme:        scheme,
+		githubNotes:   opts.GitHubNotes,
+		sizes:         opts.Sizes,
+		reactions:     opts.Reactions,
+		maxPRs:        opts.MaxPRs,
+		prompt:        terminalInput(),
+		offline:       opts.Offline,
+		upstream:      opts.Upstream,
+		showAPIStats:  opts.APIStats || opts.Verbose,
 	}).initialize()
+	if !gh.offline && gh.token == "" && gh.isSSHRemote() {
+		log.Print("no token found to call the GitHub API. " +
+			"pass --offline to build pull requests from merge and squash commits for a private repository cloned over SSH")
+	}
+	if gh.offline {
+		if err := validOffline(opts); err != nil {
+			return nil, err
+		}
+		log.Print(offlineNotice)
+	}
+	if opts.Fetch {
+		if err := gh.fetch(); err != nil {
+			return nil, err
+		}
+	}
+	if err := gh.checkShallow(opts.Unshallow); err != nil {
+		return nil, err
+	}
+	conf, err := gh.loadConfig(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	gh.config = conf
+	gh.excludePRs = make(map[int]bool)
+	for _, num := range append(opts.ExcludePRs, conf.ExcludePRs...) {
+		gh.excludePRs[num] = true
+	}
+	return gh, nil
+}
 
+func (opts *ghOpts) newRenderer(conf *config) (*renderer, error) {
+	if err := validFrontMatter(opts.FrontMatter); err != nil {
+		return nil, err
+	}
+	if err := validTitleEscape(opts.TitleEscape); err != nil {
+		return nil, err
+	}
+	if err := validGroupBy(opts.GroupBy); err != nil {
+		return nil, err
+	}
+	tmpl, err := loadTemplates(opts.TemplateDir)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := parseVars(opts.Vars)
+	if err != nil {
+		return nil, err
+	}
+	r := &renderer{
+		tmpl:           tmpl,
+		frontMatter:    opts.FrontMatter,
+		titleEscape:    opts.TitleEscape,
+		escapeMentions: opts.NoMentions,
+		escapeRefs:     opts.NoAutolinks,
+		vars:           vars,
+		collapse:       opts.Collapse,
+		titles:         conf.Titles,
+	}
 	if opts.All {
-		chlog := Changelog{}
-		vers := append(gh.versions(), "")
-		prevRev := ""
-		for _, rev := range vers {
-			r := gh.getSection(rev, prevRev)
-			if prevRev == "" && opts.NextVersion != "" {
-				r.ToRevision = opts.NextVersion
-			}
-			chlog.Sections = append(chlog.Sections, r)
-			prevRev = rev
+		r.groupBy = opts.GroupBy
+		r.toc = opts.TOC
+	}
+	return r, nil
+}
+
+// getCurrentSection returns the section between from and to, defaulting to
+// the changes since the latest version. With --milestone, it is the section
+// of the milestone instead.
+func (gh *ghch) getCurrentSection(from, to, nextVersion string) Section {
+	if gh.milestone != "" {
+		s := gh.milestoneSection(gh.milestone, nextVersion)
+		if gh.stats {
+			s.Stats = computeStats(s, time.Time{})
 		}
+		return s
+	}
+	if from == "" && to == "" {
+		from = gh.getLatestSemverTag()
+	}
+	r := gh.getSection(from, to)
+	if r.ToRevision == "" && nextVersion != "" {
+		r.ToRevision = nextVersion
+	}
+	return r
+}
 
-		if opts.Format == "markdown" {
-			results := make([]string, len(chlog.Sections))
-			for i, v := range chlog.Sections {
-				results[i], _ = v.toMkdn()
-			}
-			fmt.Fprintln(cli.OutStream, strings.Join(results, "\n\n"))
-		} else {
-			jsn, _ := json.MarshalIndent(chlog, "", "  ")
-			fmt.Fprintln(cli.OutStream, string(jsn))
+// streamJSONL prints sections one per line as soon as each is generated
+func (cli *CLI) streamJSONL(gh *ghch, opts *ghOpts) int {
+	enc := json.NewEncoder(cli.OutStream)
+	empty := true
+	emit := func(s Section) error {
+		empty = empty && s.isEmpty()
+		return enc.Encode(s)
+	}
+	var err error
+	if opts.All {
+		err = gh.eachSection(opts.NextVersion, emit)
+	} else {
+		err = emit(gh.getCurrentSection(opts.From, opts.To, opts.NextVersion))
+	}
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	return gh.exitCode(empty, opts.ExitCode || opts.Quiet)
+}
+
+// streamChangelog prints all sections as each of them is generated instead
+// of holding the whole history in memory
+func (cli *CLI) streamChangelog(gh *ghch, rdr *renderer, opts *ghOpts) int {
+	empty := true
+	each := func(fn func(Section) error) error {
+		return gh.eachSection(opts.NextVersion, func(s Section) error {
+			empty = empty && s.isEmpty()
+			return fn(s)
+		})
+	}
+	var err error
+	if opts.Format == "markdown" {
+		err = rdr.streamChangelog(cli.OutStream, each)
+		fmt.Fprintln(cli.OutStream)
+	} else {
+		err = streamChangelogJSON(cli.OutStream, each)
+	}
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	return gh.exitCode(empty, opts.ExitCode || opts.Quiet)
+}
+
+// streamChangelogJSON writes the same document as an indented Changelog
+// while marshaling one section at a time
+func streamChangelogJSON(w io.Writer, each func(func(Section) error) error) error {
+	n := 0
+	io.WriteString(w, "{\n  \"Sections\": [")
+	err := each(func(s Section) error {
+		jsn, err := json.MarshalIndent(s, "    ", "  ")
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			io.WriteString(w, ",")
 		}
+		n++
+		_, err = io.WriteString(w, "\n    "+string(jsn))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		io.WriteString(w, "\n  ")
+	}
+	_, err = io.WriteString(w, "]\n}\n")
+	return err
+}
+
+// printNumbers prints just the numbers of pull requests in ascending order,
+// one per line or as a JSON array
+func (cli *CLI) printNumbers(gh *ghch, opts *ghOpts) int {
+	var chlog Changelog
+	if opts.All {
+		chlog = gh.getChangelog(opts.NextVersion)
 	} else {
-		if opts.From == "" && opts.To == "" {
-			opts.From = gh.getLatestSemverTag()
-		}
-		r := gh.getSection(opts.From, opts.To)
-		if r.ToRevision == "" && opts.NextVersion != "" {
-			r.ToRevision = opts.NextVersion
-		}
-		if opts.Format == "markdown" {
-			str, err := r.toMkdn()
-			if err != nil {
-				log.Print(err)
-			} else {
-				fmt.Fprintln(cli.OutStream, str)
-			}
-		} else {
-			jsn, _ := json.MarshalIndent(r, "", "  ")
-			fmt.Fprintln(cli.OutStream, string(jsn))
+		chlog = Changelog{Sections: []Section{gh.getCurrentSection(opts.From, opts.To, opts.NextVersion)}}
+	}
+	writeNumbers(cli.OutStream, chlog, opts.Format == "numbers-json")
+	return gh.exitCode(chlog.isEmpty(), opts.ExitCode || opts.Quiet)
+}
+
+func writeNumbers(w io.Writer, chlog Changelog, asJSON bool) {
+	nums := []int{}
+	for _, s := range chlog.Sections {
+		for _, pr := range s.PullRequests {
+			nums = append(nums, pr.Number)
 		}
 	}
-	return exitCodeOK
+	sort.Ints(nums)
+	if asJSON {
+		jsn, _ := json.Marshal(nums)
+		fmt.Fprintln(w, string(jsn))
+		return
+	}
+	for _, n := range nums {
+		fmt.Fprintln(w, n)
+	}
 }
 
-func parseArgs(args []string) (*flags.Parser, *ghOpts, error) {
-	opts := &ghOpts{}
-	p := flags.NewParser(opts, flags.Default)
-	p.Usage = "[OPTIONS]\n\nVersion: " + version
-	_, err := p.ParseArgs(args)
-	return p, opts, err
+// getChangelog collects sections of all versions, newest first
+func (gh *ghch) getChangelog(nextVersion string) Changelog {
+	chlog := Changelog{}
+	gh.eachSection(nextVersion, func(s Section) error {
+		chlog.Sections = append(chlog.Sections, s)
+		return nil
+	})
+	return chlog
+}
+
+// eachSection generates sections of all versions newest first, passing each
+// to fn as soon as it is ready. Only the pull request numbers of the whole
+// history are held up front, to attribute each to a single section.
+func (gh *ghch) eachSection(nextVersion string, fn func(Section) error) error {
+	revs := append(gh.versions(), "")
+	nums := gh.sectionPRNums(revs)
+
+	prevRev, n := "", 0
+	for i, rev := range revs {
+		if gh.limit > 0 && n >= gh.limit {
+			break
+		}
+		r := gh.section(rev, prevRev, nums[i])
+		prevRev = rev
+		if i == 0 {
+			if nextVersion != "" {
+				r.ToRevision = nextVersion
+			} else if gh.limit > 0 && r.isEmpty() {
+				// nothing has been changed since the latest release
+				continue
+			}
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+		n++
+	}
+	return nil
 }
 
 func (gh *ghch) getSection(from, to string) Section {
-	r := gh.mergedPRs(from, to)
+	return gh.section(from, to, gh.mergedPRNums(from, to))
+}
+
+// section builds the changes between from and to. Released sections are
+// served from the cache as long as both ends point to the same commits.
+func (gh *ghch) section(from, to string, nums []int) Section {
+	key := gh.sectionCacheKey(from, to, nums)
+	if s, ok := gh.loadCachedSection(key); ok {
+		return s
+	}
+	failed := gh.failed
+	s := gh.buildSection(from, to, nums)
+	// partial sections are built again next time
+	if gh.failed == failed && !s.Incomplete {
+		gh.storeCachedSection(key, s)
+	}
+	return s
+}
+
+func (gh *ghch) buildSection(from, to string, nums []int) Section {
+	if err := gh.confirmPRCount(gh.revisionRange(from, to), len(nums)); err != nil {
+		gh.fail(err)
+		nums = nil
+	}
+	unresolved := gh.unresolvedCount()
+	r := gh.pullRequests(nums, to != "")
 	t, err := gh.getChangedAt(to)
 	if err != nil {
-		log.Print(err)
+		gh.fail(err)
 	}
 	owner, repo := gh.ownerAndRepo()
-	return Section{
+	s := Section{
+		Degraded:     gh.offline,
 		PullRequests: r,
 		FromRevision: from,
 		ToRevision:   to,
 		ChangedAt:    t,
 		Owner:        owner,
 		Repo:         repo,
+		WebURL:       gh.getWebURL(),
+	}
+	if gh.githubNotes == githubNotesMerge {
+		if notes, err := gh.fetchGitHubNotes(from, to); err != nil {
+			gh.fail(err)
+		} else {
+			gh.mergeGitHubNotes(&s, notes, to != "")
+		}
+	}
+	if gh.directCommits {
+		s.DirectCommits = gh.getDirectCommits(from, to)
+	}
+	if gh.backports {
+		gh.annotateBackports(&s)
+	}
+	s = gh.finishSection(s)
+	if gh.stats {
+		var prevAt time.Time
+		if from != "" {
+			prevAt, _ = gh.getChangedAt(from)
+		}
+		s.Stats = computeStats(s, prevAt)
+	}
+	gh.markIncomplete(&s, unresolved)
+	return s
+}
+
+// markIncomplete records on the section the errors of pull requests which
+// could not be resolved after the first n of the run
+func (gh *ghch) markIncomplete(s *Section, n int) {
+	s.Errors = gh.unresolvedSince(n)
+	s.Incomplete = len(s.Errors) > 0
+}
+
+// finishSection handles authors, components, reverts, categories, hooks,
+// highlights and translation of the pull requests, and merges extras
+func (gh *ghch) finishSection(s Section) Section {
+	if len(gh.config.Aliases) > 0 {
+		gh.canonicalizeAuthors(&s)
+	}
+	if len(gh.config.Components) > 0 {
+		gh.tagComponents(&s)
+	}
+	s.handleReverts(gh.reverts)
+	if len(gh.config.Rules) > 0 {
+		for _, pr := range s.PullRequests {
+			pr.Category = gh.config.categorize(pr)
+		}
 	}
+	s = gh.runHooks(s)
+	if len(gh.config.Rules) > 0 || hasCategory(s.PullRequests) {
+		for _, pr := range s.PullRequests {
+			if pr.Category == "" {
+				pr.Category = gh.config.categorize(pr)
+			}
+		}
+		s.Categories = groupByCategory(s.PullRequests, gh.config.categoryOrder())
+		gh.config.sortCategories(s.Categories)
+	}
+	s.Highlights = gh.config.highlight(s.PullRequests)
+	for _, pr := range s.Highlights {
+		pr.Highlight = true
+	}
+	// after rules, which are written against the original titles
+	if gh.config.Translate != nil {
+		gh.translateTitles(&s)
+	}
+	extras, err := gh.extrasOf(s.ToRevision)
+	if err != nil {
+		gh.fail(err)
+	}
+	if len(extras) > 0 {
+		gh.addExtras(&s, extras)
+	}
+	return s
+}
+
+// canonicalizeAuthors replaces authors with their aliases, so that a person
+// with several accounts or commit identities is counted once
+func (gh *ghch) canonicalizeAuthors(s *Section) {
+	for _, pr := range s.PullRequests {
+		if pr.PullRequest != nil {
+			pr.User.Login = gh.config.canonicalAuthor(pr.User.Login)
+		}
+	}
+	for _, c := range s.DirectCommits {
+		c.Author = gh.config.canonicalAuthor(c.Author, c.Email)
+	}
+	for _, c := range s.NewContributors {
+		c.Login = gh.config.canonicalAuthor(c.Login)
+	}
+}
+
+// tagComponents names the component of each pull request and drops those
+// changing only files of skipped components
+func (gh *ghch) tagComponents(s *Section) {
+	s.removePRs(func(pr *PullRequest) bool {
+		name, skip := gh.config.componentOf(pr.Files)
+		pr.Component = name
+		return skip
+	})
 }
 
 // Changelog contains Sectionst
 type Changelog struct {
 	Sections []Section `json:"Sections"`
+
+	// given by --var to templates
+	Vars map[string]string `json:"-"`
+}
+
+func (chlog Changelog) isEmpty() bool {
+	for _, s := range chlog.Sections {
+		if !s.isEmpty() {
+			return false
+		}
+	}
+	return true
 }
 
 // Section contains changes between two revisions
 type Section struct {
-	PullRequests []*octokit.PullRequest `json:"pull_requests"`
-	FromRevision string                 `json:"from_revision"`
-	ToRevision   string                 `json:"to_revision"`
-	ChangedAt    time.Time              `json:"changed_at"`
-	Owner        string                 `json:"owner"`
-	Repo         string                 `json:"repo"`
-}
+	PullRequests  []*PullRequest `json:"pull_requests"`
+	Categories    []*Category    `json:"-"`
+	DirectCommits []*Commit      `json:"direct_commits,omitempty"`
+	FromRevision  string         `json:"from_revision"`
+	ToRevision    string         `json:"to_revision"`
+	ChangedAt     time.Time      `json:"changed_at"`
+	Owner         string         `json:"owner"`
+	Repo          string         `json:"repo"`
+	Stats         *Stats         `json:"stats,omitempty"`
 
-var tmplStr = `{{$ret := . -}}
-## [{{.ToRevision}}](https://github.com/{{.Owner}}/{{.Repo}}/releases/tag/{{.ToRevision}}) ({{.ChangedAt.Format "2006-01-02"}})
-{{range .PullRequests}}
-* {{.Title}} [#{{.Number}}](https://github.com/{{$ret.Owner}}/{{$ret.Repo}}/pull/{{.Number}}) ([{{.User.Login}}](https://github.com/{{.User.Login}}))
-{{- end}}`
+	// first-time contributors detected by GitHub, with --github-notes=merge
+	NewContributors []*Contributor `json:"new_contributors,omitempty"`
+	// hand-written entries of the config
+	Extras []*Extra `json:"extras,omitempty"`
+	// pull requests picked by the highlights of the config, also in the full list
+	Highlights []*PullRequest `json:"-"`
 
-var mdTmpl *template.Template
+	// root of the links rendered in templates
+	WebURL string `json:"-"`
+	// given by --var to templates
+	Vars map[string]string `json:"-"`
 
-func init() {
-	var err error
-	mdTmpl, err = template.New("md-changelog").Parse(tmplStr)
-	if err != nil {
-		log.Fatal(err)
+	// pull requests are built from commits only, with --offline
+	Degraded bool `json:"degraded,omitempty"`
+	// some pull requests are missing or lack details for the errors
+	Incomplete bool     `json:"incomplete,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+func (rs Section) isEmpty() bool {
+	return len(rs.PullRequests) == 0 && len(rs.DirectCommits) == 0 && len(rs.Extras) == 0
+}
+
+// removePRs drops pull requests from the section including its categories
+func (rs *Section) removePRs(drop func(*PullRequest) bool) {
+	var kept []*PullRequest
+	keep := make(map[*PullRequest]bool)
+	for _, pr := range rs.PullRequests {
+		if !drop(pr) {
+			kept = append(kept, pr)
+			keep[pr] = true
+		}
+	}
+	rs.PullRequests = kept
+	var highlights []*PullRequest
+	for _, pr := range rs.Highlights {
+		if keep[pr] {
+			highlights = append(highlights, pr)
+		}
+	}
+	rs.Highlights = highlights
+	var cats []*Category
+	for _, c := range rs.Categories {
+		var prs []*PullRequest
+		for _, pr := range c.PullRequests {
+			if keep[pr] {
+				prs = append(prs, pr)
+			}
+		}
+		if c.PullRequests = prs; len(prs) > 0 {
+			cats = append(cats, c)
+		}
 	}
+	rs.Categories = cats
 }
 
-func (rs Section) toMkdn() (string, error) {
-	var b bytes.Buffer
-	err := mdTmpl.Execute(&b, rs)
-	if err != nil {
-		return "", err
+// dedupeNums attributes each pull request number of sections ordered newest
+// first only to the oldest section it appears in, since tags on branched
+// histories can make ranges overlap
+func dedupeNums(sections [][]int) {
+	seen := make(map[int]bool)
+	for i := len(sections) - 1; i >= 0; i-- {
+		var kept []int
+		for _, num := range sections[i] {
+			if !seen[num] {
+				seen[num] = true
+				kept = append(kept, num)
+			}
+		}
+		sections[i] = kept
 	}
-	return b.String(), nil
 }
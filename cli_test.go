@@ -0,0 +1,82 @@
+package ghch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestLimitWithWrite(t *testing.T) {
+	opts := &ghOpts{Limit: 3, Write: true}
+	if _, err := opts.newGhch(); err == nil {
+		t.Error("--limit with --write should be an error")
+	}
+}
+
+func TestEachSectionLimit(t *testing.T) {
+	dir := testOrigin(t)
+	testGit(t, dir, "commit", "-q", "--allow-empty", "-m", "release v0.3.0")
+	testGit(t, dir, "tag", "v0.3.0")
+
+	collect := func(limit int, nextVersion string) []string {
+		gh := (&ghch{repoPath: dir, gitPath: "git", directCommits: true, limit: limit, config: &config{}}).initialize()
+		var revs []string
+		if err := gh.eachSection(nextVersion, func(s Section) error {
+			revs = append(revs, s.ToRevision)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return revs
+	}
+	testCases := []struct {
+		name        string
+		limit       int
+		nextVersion string
+		expect      []string
+	}{
+		{"no unreleased changes", 2, "", []string{"v0.3.0", "v0.2.0"}},
+		{"next version", 1, "v0.4.0", []string{"v0.4.0"}},
+		{"over the versions", 5, "", []string{"v0.3.0", "v0.2.0", "v0.1.0"}},
+	}
+	for _, tc := range testCases {
+		if got := collect(tc.limit, tc.nextVersion); !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("%s: got %v, expect %v", tc.name, got, tc.expect)
+		}
+	}
+
+	testGit(t, dir, "commit", "-q", "--allow-empty", "-m", "Fix a typo")
+	if got, expect := collect(2, ""), []string{"", "v0.3.0"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("unreleased changes: got %v, expect %v", got, expect)
+	}
+}
+
+func TestWriteNumbers(t *testing.T) {
+	prs := func(nums ...int) []*PullRequest {
+		var prs []*PullRequest
+		for _, n := range nums {
+			prs = append(prs, &PullRequest{PullRequest: &octokit.PullRequest{Number: n}})
+		}
+		return prs
+	}
+	chlog := Changelog{Sections: []Section{{PullRequests: prs(12, 10)}, {PullRequests: prs(3, 11)}}}
+	testCases := []struct {
+		chlog  Changelog
+		asJSON bool
+		expect string
+	}{
+		{chlog, false, "3\n10\n11\n12\n"},
+		{chlog, true, "[3,10,11,12]\n"},
+		{Changelog{}, false, ""},
+		{Changelog{}, true, "[]\n"},
+	}
+	for _, tc := range testCases {
+		var b strings.Builder
+		writeNumbers(&b, tc.chlog, tc.asJSON)
+		if got := b.String(); got != tc.expect {
+			t.Errorf("asJSON=%v: got %q, expect %q", tc.asJSON, got, tc.expect)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package ghch
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// compareCommit is a commit listed by the compare API
+type compareCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+	} `json:"commit"`
+	Parents []struct {
+		SHA string `json:"sha"`
+	} `json:"parents"`
+}
+
+// compareRevision returns the name of rev known to GitHub
+func (gh *ghch) compareRevision(rev string) string {
+	if rev != "" {
+		return rev
+	}
+	if gh.branch != "" {
+		// GitHub only knows the branch of the remote-tracking one
+		return strings.TrimPrefix(gh.branch, gh.getRemote()+"/")
+	}
+	// a local HEAD is meaningless to the API, so it is passed as a commit
+	sha, _ := gh.cmd("rev-parse", "HEAD")
+	return strings.TrimSpace(sha)
+}
+
+// comparePRNums lists pull request numbers between from and to with the
+// compare API instead of the local history
+func (gh *ghch) comparePRNums(from, to string) (nums []int, err error) {
+	if from == "" {
+		// the API has no notion of the root, so take it from the local history
+		from, _ = gh.cmd("rev-list", "--max-parents=0", gh.compareRevision(to))
+		from = strings.TrimSpace(from)
+	}
+	toRev := gh.compareRevision(to)
+	nums, err = gh.compareRange(from, toRev)
+	if aerr, ok := errors.Cause(err).(*apiError); ok && aerr.StatusCode == http.StatusNotFound {
+		// tags created just before by a release pipeline may not be pushed yet
+		fromSHA, toSHA := gh.commitOf(from), gh.commitOf(toRev)
+		if fromSHA != "" && toSHA != "" {
+			log.Printf("%s...%s is not found on GitHub. comparing their commits instead, assuming unpushed tags", from, toRev)
+			return gh.compareRange(fromSHA, toSHA)
+		}
+	}
+	return nums, err
+}
+
+// commitOf resolves rev to its commit locally, or returns ""
+func (gh *ghch) commitOf(rev string) string {
+	if t, ok := gh.tagRefs()[rev]; ok {
+		return t.SHA
+	}
+	sha, err := gh.cmd("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(sha)
+}
+
+func (gh *ghch) compareRange(from, to string) (nums []int, err error) {
+	owner, repo := gh.ownerAndRepo()
+	path := fmt.Sprintf("repos/%s/%s/compare/%s...%s?per_page=100",
+		owner, repo, url.PathEscape(from), url.PathEscape(to))
+	var commits []compareCommit
+	for path != "" {
+		var page struct {
+			Commits []compareCommit `json:"commits"`
+		}
+		if path, err = gh.apiGet(path, &page); err != nil {
+			return nil, errors.Wrapf(err, "failed to compare %s...%s", from, to)
+		}
+		commits = append(commits, page.Commits...)
+	}
+	return parseCompareCommits(commits, gh.scanRefs), nil
+}
+
+// parseCompareCommits picks pull request numbers up in the same way as from
+// `git log`, which lists commits newest first unlike the compare API
+func parseCompareCommits(commits []compareCommit, scanRefs bool) (nums []int) {
+	var refs []int
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		subject := splitLines(c.Commit.Message)[0]
+		if len(c.Parents) > 1 {
+			if matches := prMergeSubjectReg.FindStringSubmatch(subject); len(matches) > 1 {
+				n, _ := strconv.Atoi(matches[1])
+				nums = appendUniqueNums(nums, n)
+			}
+		}
+		if scanRefs {
+			refs = append(refs, parsePRRefs(c.Commit.Message)...)
+		}
+	}
+	return appendUniqueNums(nums, refs...)
+}
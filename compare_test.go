@@ -0,0 +1,70 @@
+package ghch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCompareCommits(t *testing.T) {
+	// commits of the compare API are ordered oldest first
+	input := `[
+  {"sha": "c1", "commit": {"message": "Add foo (#3)"}, "parents": [{"sha": "root"}]},
+  {"sha": "m1", "commit": {"message": "Merge pull request #1 from foo/bar\n\nbar"}, "parents": [{"sha": "root"}, {"sha": "b1"}]},
+  {"sha": "c2", "commit": {"message": "Merge pull request #9 from foo/squashed"}, "parents": [{"sha": "m1"}]},
+  {"sha": "m2", "commit": {"message": "Merge pull request #2 from foo/baz"}, "parents": [{"sha": "c2"}, {"sha": "b2"}]}
+]`
+	var commits []compareCommit
+	if err := json.Unmarshal([]byte(input), &commits); err != nil {
+		t.Fatal(err)
+	}
+	if got := parseCompareCommits(commits, false); !reflect.DeepEqual(got, []int{2, 1}) {
+		t.Errorf("got %v, expect [2 1]", got)
+	}
+	if got := parseCompareCommits(commits, true); !reflect.DeepEqual(got, []int{2, 1, 3}) {
+		t.Errorf("got %v, expect [2 1 3]", got)
+	}
+}
+
+func TestComparePRNumsUnpushedTags(t *testing.T) {
+	dir := testOrigin(t)
+	remote := filepath.Join(filepath.Dir(dir), "o", "r.git")
+	testGit(t, dir, "init", "-q", "--bare", remote)
+	testGit(t, dir, "remote", "add", "origin", remote)
+	testGit(t, dir, "push", "-q", "origin", "HEAD", "v0.1.0")
+	from, to := testGit(t, dir, "rev-parse", "v0.1.0"), testGit(t, dir, "rev-parse", "v0.2.0")
+
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path != "/repos/o/r/compare/"+from+"..."+to {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"commits": [{"sha": "m1", "commit": {"message": "Merge pull request #1 from o/feature"}, "parents": [{"sha": "p1"}, {"sha": "p2"}]}]}`)
+	}))
+	defer ts.Close()
+
+	gh := (&ghch{repoPath: dir, gitPath: "git", apiURL: ts.URL, config: &config{}}).initialize()
+	got, err := gh.comparePRNums("v0.1.0", "v0.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("got %v, expect [1]", got)
+	}
+	if len(requested) != 2 {
+		t.Errorf("the tags and then their commits should be compared: %v", requested)
+	}
+
+	if !gh.isTagPushed("v0.1.0") {
+		t.Error("v0.1.0 is pushed")
+	}
+	if gh.isTagPushed("v0.2.0") {
+		t.Error("v0.2.0 is not pushed")
+	}
+}
@@ -0,0 +1,525 @@
+package ghch
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultConfigFile = ".ghch.yml"
+	defaultCategory   = "Other Changes"
+)
+
+// config is loaded from .ghch.yml of the repository
+type config struct {
+	Rules           []*rule `yaml:"rules"`
+	DefaultCategory string  `yaml:"default_category"`
+	Hooks           []*hook `yaml:"hooks"`
+	// order of categories in the output and sorting of their pull requests
+	Categories []*categoryConfig `yaml:"categories"`
+	// sizes of pull requests by the lines changed, smallest first
+	Sizes []*sizeRule `yaml:"sizes"`
+	// components of pull requests by their changed files
+	Components []*component `yaml:"components"`
+	// canonical logins of other logins, names and emails of authors
+	Aliases map[string]string `yaml:"aliases"`
+	// numbers of pull requests to leave out
+	ExcludePRs []int `yaml:"exclude_pull_requests"`
+	// pull requests to be summarized before the full list
+	Highlights *highlights `yaml:"highlights"`
+	// normalization of titles in the output
+	Titles *titleRules `yaml:"titles"`
+	// translation of titles of pull requests
+	Translate *translator `yaml:"translate"`
+	// hand-written entries, also read from <version>.yml in ExtrasDir
+	Extras    []*Extra `yaml:"extras"`
+	ExtrasDir string   `yaml:"extras_dir"`
+}
+
+// component tags pull requests changing any file matching Paths. Pull
+// requests changing only files of skipped components are left out.
+type component struct {
+	Name  string   `yaml:"name"`
+	Paths []string `yaml:"paths"`
+	Skip  bool     `yaml:"skip"`
+}
+
+// categoryConfig places a category in the output and sorts its pull requests
+// by Sort, one of prSortKeys optionally prefixed with "-" for descending
+// order. The name "*" stands for the categories not listed.
+type categoryConfig struct {
+	Name string `yaml:"name"`
+	Sort string `yaml:"sort"`
+}
+
+// prSortKeys compare pull requests within a category
+var prSortKeys = map[string]func(a, b *PullRequest) bool{
+	"number": func(a, b *PullRequest) bool { return a.Number < b.Number },
+	"title": func(a, b *PullRequest) bool {
+		return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+	},
+	"author": func(a, b *PullRequest) bool {
+		return strings.ToLower(a.User.Login) < strings.ToLower(b.User.Login)
+	},
+	"merged_at": func(a, b *PullRequest) bool {
+		return a.MergedAt != nil && (b.MergedAt == nil || a.MergedAt.Before(*b.MergedAt)) ||
+			a.MergedAt == nil && b.MergedAt == nil && a.Number < b.Number
+	},
+}
+
+// rule maps pull requests to a category. All of the given conditions must be
+// met, and any of the values of a list condition is enough. The first
+// matching rule wins.
+type rule struct {
+	Category string   `yaml:"category"`
+	Labels   []string `yaml:"labels"`
+	Title    string   `yaml:"title"`
+	Authors  []string `yaml:"authors"`
+	Paths    []string `yaml:"paths"`
+
+	titleReg *regexp.Regexp
+}
+
+// highlights picks pull requests matching any of the rules, up to Max of
+// them if given, in the order they were merged. Ordered by "reactions", the
+// most 👍 ones are picked.
+type highlights struct {
+	Rules []*rule `yaml:"rules"`
+	Max   int     `yaml:"max"`
+	Order string  `yaml:"order"`
+}
+
+// loadConfig reads the config file. A missing file is not an error unless it
+// was explicitly specified.
+func loadConfig(file string, explicit bool) (*config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return &config{}, nil
+		}
+		return nil, errors.Wrap(err, "failed to read config")
+	}
+	return parseConfig(b, file)
+}
+
+func parseConfig(b []byte, file string) (*config, error) {
+	conf := &config{}
+	if err := yaml.Unmarshal(b, conf); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", file)
+	}
+	if err := conf.compile(); err != nil {
+		return nil, errors.Wrapf(err, "invalid config %s", file)
+	}
+	return conf, nil
+}
+
+// loadConfig loads the config file given by --config, or the default one of
+// the repository. Bare repositories have it in the tree of the head.
+func (gh *ghch) loadConfig(file string) (*config, error) {
+	if file != "" {
+		return loadConfig(file, true)
+	}
+	if !gh.isBare() {
+		return loadConfig(gh.repoFile(defaultConfigFile), false)
+	}
+	if out, _ := gh.cmd("ls-tree", "--name-only", gh.head(), defaultConfigFile); strings.TrimSpace(out) == "" {
+		return &config{}, nil
+	}
+	out, err := gh.cmd("show", gh.head()+":"+defaultConfigFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config. `git show` failed")
+	}
+	return parseConfig([]byte(out), defaultConfigFile)
+}
+
+func (conf *config) compile() error {
+	for i, r := range conf.Rules {
+		if r.Category == "" {
+			return errors.Errorf("rules[%d]: category is required", i)
+		}
+		if err := r.compile(); err != nil {
+			return errors.Wrapf(err, "rules[%d]", i)
+		}
+	}
+	if conf.Highlights != nil {
+		if o := conf.Highlights.Order; o != "" && o != "reactions" {
+			return errors.Errorf("highlights: unknown order %q: must be reactions", o)
+		}
+		for i, r := range conf.Highlights.Rules {
+			if err := r.compile(); err != nil {
+				return errors.Wrapf(err, "highlights.rules[%d]", i)
+			}
+		}
+	}
+	rest := false
+	for i, c := range conf.Categories {
+		if c.Name == "" {
+			return errors.Errorf("categories[%d]: name is required", i)
+		}
+		if c.Name == "*" {
+			if rest {
+				return errors.Errorf("categories[%d]: \"*\" is listed twice", i)
+			}
+			rest = true
+		}
+		if _, ok := prSortKeys[strings.TrimPrefix(c.Sort, "-")]; c.Sort != "" && !ok {
+			return errors.Errorf("categories[%d]: unknown sort %q: must be number, title, author or merged_at", i, c.Sort)
+		}
+	}
+	if conf.Translate != nil {
+		if err := conf.Translate.validate(); err != nil {
+			return err
+		}
+	}
+	if conf.Titles != nil {
+		if err := conf.Titles.compile(); err != nil {
+			return err
+		}
+	}
+	if err := validSizes(conf.Sizes); err != nil {
+		return err
+	}
+	if err := validExtras(conf.Extras); err != nil {
+		return err
+	}
+	for i, c := range conf.Components {
+		if len(c.Paths) == 0 {
+			return errors.Errorf("components[%d]: paths are required", i)
+		}
+		if c.Name == "" && !c.Skip {
+			return errors.Errorf("components[%d]: name is required unless skipped", i)
+		}
+	}
+	for i, h := range conf.Hooks {
+		if err := h.validate(); err != nil {
+			return errors.Wrapf(err, "hooks[%d]", i)
+		}
+	}
+	return nil
+}
+
+func (r *rule) compile() error {
+	if r.Title != "" {
+		reg, err := regexp.Compile(r.Title)
+		if err != nil {
+			return errors.Wrap(err, "invalid title pattern")
+		}
+		r.titleReg = reg
+	}
+	return nil
+}
+
+// allRules returns the rules of categories and highlights
+func (conf *config) allRules() []*rule {
+	if conf.Highlights == nil {
+		return conf.Rules
+	}
+	return append(append([]*rule{}, conf.Rules...), conf.Highlights.Rules...)
+}
+
+func (conf *config) needsLabels() bool {
+	for _, r := range conf.allRules() {
+		if len(r.Labels) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (conf *config) needsFiles() bool {
+	if len(conf.Components) > 0 {
+		return true
+	}
+	for _, r := range conf.allRules() {
+		if len(r.Paths) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rule) match(pr *PullRequest) bool {
+	if len(r.Labels) > 0 && !containsAny(r.Labels, pr.Labels) {
+		return false
+	}
+	if r.titleReg != nil && !r.titleReg.MatchString(pr.Title) {
+		return false
+	}
+	if len(r.Authors) > 0 && !containsAny(r.Authors, []string{pr.User.Login}) {
+		return false
+	}
+	if len(r.Paths) > 0 && !anyPathMatch(r.Paths, pr.Files) {
+		return false
+	}
+	return true
+}
+
+// componentOf returns the first component other than skipped ones matching
+// any changed file, and whether the pull request changes only skipped files
+func (conf *config) componentOf(files []string) (name string, skip bool) {
+	skipped := 0
+	for _, f := range files {
+		for _, c := range conf.Components {
+			if c.Skip && anyPathMatch(c.Paths, []string{f}) {
+				skipped++
+				break
+			}
+		}
+	}
+	if len(files) > 0 && skipped == len(files) {
+		return "", true
+	}
+	for _, c := range conf.Components {
+		if !c.Skip && anyPathMatch(c.Paths, files) {
+			return c.Name, false
+		}
+	}
+	return "", false
+}
+
+// canonicalAuthor returns what the first of ids having an alias maps to, or
+// the first one. Aliases are looked up case insensitively.
+func (conf *config) canonicalAuthor(ids ...string) string {
+	if canonical, ok := conf.aliasOf(ids...); ok {
+		return canonical
+	}
+	return ids[0]
+}
+
+// aliasOf returns what the first of ids having an alias maps to
+func (conf *config) aliasOf(ids ...string) (string, bool) {
+	for _, id := range ids {
+		for alias, canonical := range conf.Aliases {
+			if id != "" && strings.EqualFold(alias, id) {
+				return canonical, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (conf *config) needsReactions() bool {
+	return conf.Highlights != nil && conf.Highlights.Order == "reactions"
+}
+
+// highlight picks the pull requests matching any rule of the highlights.
+// Without rules, the ones with any 👍 are candidates of the reactions order.
+func (conf *config) highlight(prs []*PullRequest) []*PullRequest {
+	if conf.Highlights == nil {
+		return nil
+	}
+	if conf.needsReactions() {
+		prs = sortByReactions(prs)
+	} else {
+		// the section may be in any order, which must not change the picks
+		prs = append([]*PullRequest{}, prs...)
+		sort.SliceStable(prs, func(i, j int) bool { return prSortKeys["merged_at"](prs[i], prs[j]) })
+	}
+	var picked []*PullRequest
+	for _, pr := range prs {
+		if conf.Highlights.Max > 0 && len(picked) >= conf.Highlights.Max {
+			break
+		}
+		if len(conf.Highlights.Rules) == 0 {
+			if conf.needsReactions() && pr.ThumbsUp > 0 {
+				picked = append(picked, pr)
+			}
+			continue
+		}
+		for _, r := range conf.Highlights.Rules {
+			if r.match(pr) {
+				picked = append(picked, pr)
+				break
+			}
+		}
+	}
+	return picked
+}
+
+func (conf *config) categorize(pr *PullRequest) string {
+	for _, r := range conf.Rules {
+		if r.match(pr) {
+			return r.Category
+		}
+	}
+	return conf.defaultCategoryName()
+}
+
+func (conf *config) defaultCategoryName() string {
+	if conf.DefaultCategory != "" {
+		return conf.DefaultCategory
+	}
+	return defaultCategory
+}
+
+// categoryOrder lists categories in the order of the categories config.
+// Others are in the order of the rules followed by the default one, in place
+// of "*" or after the listed ones.
+func (conf *config) categoryOrder() []string {
+	order := conf.ruleCategoryOrder()
+	if len(conf.Categories) == 0 {
+		return order
+	}
+	listed := make(map[string]bool)
+	for _, c := range conf.Categories {
+		listed[c.Name] = true
+	}
+	var rest []string
+	for _, name := range order {
+		if !listed[name] {
+			rest = append(rest, name)
+		}
+	}
+	var ret []string
+	for _, c := range conf.Categories {
+		if c.Name == "*" {
+			ret, rest = append(ret, rest...), nil
+		} else {
+			ret = append(ret, c.Name)
+		}
+	}
+	return append(ret, rest...)
+}
+
+// sortCategories sorts pull requests of each category as configured
+func (conf *config) sortCategories(cats []*Category) {
+	sorts := make(map[string]string)
+	for _, c := range conf.Categories {
+		sorts[c.Name] = c.Sort
+	}
+	for _, cat := range cats {
+		key, ok := sorts[cat.Name]
+		if !ok {
+			key = sorts["*"]
+		}
+		if key == "" {
+			continue
+		}
+		less := prSortKeys[strings.TrimPrefix(key, "-")]
+		desc := strings.HasPrefix(key, "-")
+		sort.SliceStable(cat.PullRequests, func(i, j int) bool {
+			if desc {
+				return less(cat.PullRequests[j], cat.PullRequests[i])
+			}
+			return less(cat.PullRequests[i], cat.PullRequests[j])
+		})
+	}
+}
+
+func (conf *config) ruleCategoryOrder() []string {
+	var order []string
+	seen := make(map[string]bool)
+	for _, r := range conf.Rules {
+		if !seen[r.Category] {
+			seen[r.Category] = true
+			order = append(order, r.Category)
+		}
+	}
+	def := conf.defaultCategoryName()
+	if !seen[def] {
+		order = append(order, def)
+	}
+	return order
+}
+
+func containsAny(want, have []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if strings.EqualFold(w, h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyPathMatch(patterns, files []string) bool {
+	for _, p := range patterns {
+		for _, f := range files {
+			if matchPath(p, f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPath matches a slash separated path against a glob pattern in which
+// "**" matches any number of directories. Patterns without a slash are
+// matched against the base name, like .gitignore does.
+func matchPath(pattern, file string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(file))
+		return ok
+	}
+	return globRegexp(pattern).MatchString(file)
+}
+
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// Category groups pull requests of a section
+type Category struct {
+	Name         string
+	PullRequests []*PullRequest
+	// rendered in <details> with --collapse
+	Collapsed bool
+	Extras    []*Extra
+}
+
+func groupByCategory(prs []*PullRequest, order []string) []*Category {
+	var cats []*Category
+	idx := make(map[string]*Category)
+	for _, name := range order {
+		c := &Category{Name: name}
+		idx[name] = c
+		cats = append(cats, c)
+	}
+	for _, pr := range prs {
+		c, ok := idx[pr.Category]
+		if !ok {
+			c = &Category{Name: pr.Category}
+			idx[pr.Category] = c
+			cats = append(cats, c)
+		}
+		c.PullRequests = append(c.PullRequests, pr)
+	}
+	var ret []*Category
+	for _, c := range cats {
+		if len(c.PullRequests) > 0 {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,281 @@
+package ghch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestMatchPath(t *testing.T) {
+	testCases := []struct {
+		pattern, file string
+		expect        bool
+	}{
+		{"cmd/**", "cmd/ghch/ghch.go", true},
+		{"cmd/**", "ghch.go", false},
+		{"docs/**/*.md", "docs/README.md", true},
+		{"docs/**/*.md", "docs/a/b/usage.md", true},
+		{"*.md", "docs/a/usage.md", true},
+		{"*.md", "ghch.go", false},
+		{"cli.go", "cli.go", true},
+	}
+	for _, tc := range testCases {
+		if got := matchPath(tc.pattern, tc.file); got != tc.expect {
+			t.Errorf("matchPath(%q, %q): got %v, expect %v", tc.pattern, tc.file, got, tc.expect)
+		}
+	}
+}
+
+func TestConfigCategorize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghch-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, ".ghch.yml")
+	ioutil.WriteFile(file, []byte(`rules:
+  - category: Breaking Changes
+    labels: [breaking]
+  - category: Features
+    labels: [enhancement, feature]
+  - category: Fixes
+    title: '(?i)^fix'
+  - category: Dependencies
+    authors: ['dependabot[bot]']
+  - category: Documentation
+    paths: ['docs/**', '*.md']
+default_category: Misc
+`), 0644)
+
+	conf, err := loadConfig(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPR := func(num int, title, login string, labels, files []string) *PullRequest {
+		return &PullRequest{
+			PullRequest: &octokit.PullRequest{Number: num, Title: title, User: octokit.User{Login: login}},
+			Labels:      labels,
+			Files:       files,
+		}
+	}
+	prs := []*PullRequest{
+		newPR(1, "Fix typo", "yukiyan", nil, []string{"cli.go"}),
+		newPR(2, "Add --all", "Songmu", []string{"Enhancement"}, nil),
+		newPR(3, "Fix API breakage", "Songmu", []string{"breaking", "bug"}, nil),
+		newPR(4, "Bump go-flags", "dependabot[bot]", nil, nil),
+		newPR(5, "Update usage", "Songmu", nil, []string{"docs/usage.md"}),
+		newPR(6, "Refactor", "Songmu", nil, []string{"ghch.go"}),
+	}
+	expect := []string{"Fixes", "Features", "Breaking Changes", "Dependencies", "Documentation", "Misc"}
+	for i, pr := range prs {
+		pr.Category = conf.categorize(pr)
+		if pr.Category != expect[i] {
+			t.Errorf("#%d: got category %q, expect %q", pr.Number, pr.Category, expect[i])
+		}
+	}
+
+	var names []string
+	for _, c := range groupByCategory(prs, conf.categoryOrder()) {
+		names = append(names, c.Name)
+	}
+	expectOrder := []string{"Breaking Changes", "Features", "Fixes", "Dependencies", "Documentation", "Misc"}
+	if !reflect.DeepEqual(names, expectOrder) {
+		t.Errorf("categories: got %v, expect %v", names, expectOrder)
+	}
+
+	if _, err := loadConfig(filepath.Join(dir, "missing.yml"), false); err != nil {
+		t.Errorf("missing default config should be ignored: %s", err)
+	}
+	if _, err := loadConfig(filepath.Join(dir, "missing.yml"), true); err == nil {
+		t.Errorf("missing explicit config should be an error")
+	}
+}
+
+func TestConfigCategories(t *testing.T) {
+	conf, err := parseConfig([]byte(`rules:
+  - category: Dependencies
+    authors: ['dependabot[bot]']
+  - category: Fixes
+    title: '(?i)^fix'
+  - category: Features
+    labels: [enhancement]
+categories:
+  - name: Breaking Changes
+  - name: Features
+    sort: title
+  - name: '*'
+    sort: -number
+  - name: Dependencies
+`), defaultConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"Breaking Changes", "Features", "Fixes", "Other Changes", "Dependencies"}
+	if got := conf.categoryOrder(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+
+	newPR := func(num int, title, category string) *PullRequest {
+		return &PullRequest{PullRequest: &octokit.PullRequest{Number: num, Title: title}, Category: category}
+	}
+	prs := []*PullRequest{
+		newPR(1, "Fix typo", "Fixes"),
+		newPR(2, "Bump go-flags", "Dependencies"),
+		newPR(3, "add --all", "Features"),
+		newPR(4, "Fix API breakage", "Fixes"),
+		newPR(5, "Add --stats", "Features"),
+	}
+	cats := groupByCategory(prs, conf.categoryOrder())
+	conf.sortCategories(cats)
+	var got []int
+	for _, c := range cats {
+		for _, pr := range c.PullRequests {
+			got = append(got, pr.Number)
+		}
+	}
+	if expect := []int{3, 5, 4, 1, 2}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+
+	for _, conf := range []string{
+		"categories:\n  - sort: title\n",
+		"categories:\n  - name: Fixes\n    sort: size\n",
+		"categories:\n  - name: '*'\n  - name: '*'\n",
+	} {
+		if _, err := parseConfig([]byte(conf), defaultConfigFile); err == nil {
+			t.Errorf("invalid config should be an error: %s", conf)
+		}
+	}
+}
+
+func TestConfigComponentOf(t *testing.T) {
+	conf, err := parseConfig([]byte(`components:
+  - name: CLI
+    paths: ['cmd/**']
+  - paths: ['docs/**', '*.md']
+    skip: true
+  - name: Core
+    paths: ['*.go']
+`), defaultConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		files      []string
+		expect     string
+		expectSkip bool
+	}{
+		{[]string{"cmd/ghch/main.go", "ghch.go"}, "CLI", false},
+		{[]string{"README.md", "ghch.go"}, "Core", false},
+		{[]string{"docs/usage.md", "CHANGELOG.md"}, "", true},
+		{[]string{".travis.yml"}, "", false},
+		{nil, "", false},
+	}
+	for _, tc := range testCases {
+		name, skip := conf.componentOf(tc.files)
+		if name != tc.expect || skip != tc.expectSkip {
+			t.Errorf("%v: got (%q, %v), expect (%q, %v)", tc.files, name, skip, tc.expect, tc.expectSkip)
+		}
+	}
+	if _, err := parseConfig([]byte("components:\n  - paths: ['cmd/**']\n"), defaultConfigFile); err == nil {
+		t.Error("a component without its name should be invalid unless skipped")
+	}
+}
+
+func TestConfigCanonicalAuthor(t *testing.T) {
+	conf, err := parseConfig([]byte(`aliases:
+  songmu-work: Songmu
+  Masayuki Matsuki: Songmu
+  y@songmu.jp: Songmu
+`), defaultConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		ids    []string
+		expect string
+	}{
+		{[]string{"Songmu-Work"}, "Songmu"},
+		{[]string{"Masayuki Matsuki", "m@example.com"}, "Songmu"},
+		{[]string{"songmu", "y@songmu.jp"}, "Songmu"},
+		{[]string{"yukiyan", ""}, "yukiyan"},
+	}
+	for _, tc := range testCases {
+		if got := conf.canonicalAuthor(tc.ids...); got != tc.expect {
+			t.Errorf("%v: got %q, expect %q", tc.ids, got, tc.expect)
+		}
+	}
+}
+
+func TestConfigHighlight(t *testing.T) {
+	conf, err := parseConfig([]byte(`highlights:
+  rules:
+    - labels: [highlight]
+    - title: '(?i)^breaking'
+  max: 2
+`), defaultConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conf.needsLabels() {
+		t.Error("labels should be fetched for highlights")
+	}
+	newPR := func(num int, title string, labels ...string) *PullRequest {
+		return &PullRequest{PullRequest: &octokit.PullRequest{Number: num, Title: title}, Labels: labels}
+	}
+	prs := []*PullRequest{
+		newPR(1, "Fix typo"),
+		newPR(2, "Add --all", "highlight"),
+		newPR(3, "Breaking: drop Go 1.9"),
+		newPR(4, "Add --toc", "Highlight"),
+	}
+	var nums []int
+	for _, pr := range conf.highlight(prs) {
+		nums = append(nums, pr.Number)
+	}
+	if expect := []int{2, 3}; !reflect.DeepEqual(nums, expect) {
+		t.Errorf("got %v, expect %v", nums, expect)
+	}
+
+	// picked in the order of merge, whatever the order of the section is
+	for i, pr := range prs {
+		mergedAt := time.Date(2024, 6, 5-i, 0, 0, 0, 0, time.UTC)
+		pr.MergedAt = &mergedAt
+	}
+	nums = nil
+	for _, pr := range conf.highlight(prs) {
+		nums = append(nums, pr.Number)
+	}
+	if expect := []int{4, 3}; !reflect.DeepEqual(nums, expect) {
+		t.Errorf("got %v, expect %v", nums, expect)
+	}
+}
+
+func TestConfigHighlightByReactions(t *testing.T) {
+	conf, err := parseConfig([]byte("highlights:\n  order: reactions\n  max: 2\n"), defaultConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPR := func(num, thumbsUp, comments int) *PullRequest {
+		return &PullRequest{PullRequest: &octokit.PullRequest{Number: num}, ThumbsUp: thumbsUp, Comments: comments}
+	}
+	prs := []*PullRequest{newPR(1, 0, 9), newPR(2, 3, 0), newPR(3, 5, 1), newPR(4, 3, 2)}
+	var nums []int
+	for _, pr := range conf.highlight(prs) {
+		nums = append(nums, pr.Number)
+	}
+	if expect := []int{3, 4}; !reflect.DeepEqual(nums, expect) {
+		t.Errorf("got %v, expect %v", nums, expect)
+	}
+	if prs[0].Number != 1 {
+		t.Error("pull requests of the section should keep their order")
+	}
+	if _, err := parseConfig([]byte("highlights:\n  order: stars\n"), defaultConfigFile); err == nil {
+		t.Error("unknown order should be invalid")
+	}
+}
@@ -0,0 +1,188 @@
+package ghch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type digestOpts struct {
+	ghOpts
+	Weeks int `long:"weeks" default:"4" description:"number of ISO weeks to digest, up to the current one"`
+}
+
+// runDigest prints pull requests merged in the last weeks grouped by ISO
+// week regardless of tags, for periodic updates rather than release notes.
+// It is markdown unless --format=json is given.
+func (cli *CLI) runDigest(argv []string) int {
+	opts := &digestOpts{}
+	p, _, err := parseCommandArgs("digest", opts, argv)
+	if err != nil {
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+	if !p.FindOptionByLongName("format").IsSet() {
+		opts.Format = "markdown"
+	}
+	if opts.Format != "markdown" && opts.Format != "json" {
+		log.Printf("unsupported format %q for digest: must be markdown or json", opts.Format)
+		return exitCodeParseFlagError
+	}
+	if opts.Weeks < 1 {
+		log.Print("--weeks must be 1 or more")
+		return exitCodeParseFlagError
+	}
+
+	gh, err := opts.newGhch()
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	rdr, err := opts.newRenderer(gh.config)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	weeks := gh.digest(opts.Weeks, time.Now())
+	if opts.Format == "json" {
+		if weeks == nil {
+			weeks = []*digestWeek{}
+		}
+		jsn, _ := json.MarshalIndent(weeks, "", "  ")
+		fmt.Fprintln(cli.OutStream, string(jsn))
+	} else {
+		str, err := rdr.digest(weeks)
+		if err != nil {
+			log.Print(err)
+			return exitCodeErr
+		}
+		fmt.Fprintln(cli.OutStream, str)
+	}
+	return gh.exitCode(len(weeks) == 0, opts.ExitCode || opts.Quiet)
+}
+
+// digestWeek is an ISO week of a digest, starting on Monday
+type digestWeek struct {
+	// like 2024-W23
+	Week  string    `json:"week"`
+	Start time.Time `json:"start"`
+	// the last day of the week
+	End     time.Time `json:"end"`
+	Section Section   `json:"section"`
+}
+
+// weekStart returns the midnight of the Monday of the week of t
+func weekStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+func newDigestWeek(start time.Time) *digestWeek {
+	y, w := start.ISOWeek()
+	return &digestWeek{Week: fmt.Sprintf("%d-W%02d", y, w), Start: start, End: start.AddDate(0, 0, 6)}
+}
+
+// digest collects pull requests merged into the head in the last weeks up
+// to now, newest week first, leaving out weeks without changes
+func (gh *ghch) digest(weeks int, now time.Time) []*digestWeek {
+	since := weekStart(now).AddDate(0, 0, -7*(weeks-1))
+	after := "--since=" + since.Format(time.RFC3339)
+	out, err := gh.cmd("log", gh.head(), after, "--merges", "--format=%x1e%H%x00%s")
+	if err != nil {
+		gh.fail(errors.Wrap(err, "failed to list merged pull requests. `git log` failed"))
+		return nil
+	}
+	nums := gh.recordPRCommits(out, parseMergeSubject)
+	if gh.offline {
+		nums = appendUniqueNums(nums, gh.squashPRNums(gh.head(), after)...)
+	}
+	if gh.scanRefs {
+		if out, err := gh.cmd("log", gh.head(), after, "--format=%x1e%H%x00%B"); err == nil {
+			nums = appendUniqueNums(nums, gh.recordPRCommits(out, parsePRRefs)...)
+		}
+	}
+	if err := gh.confirmPRCount(fmt.Sprintf("digest of %d weeks", weeks), len(nums)); err != nil {
+		gh.fail(err)
+		return nil
+	}
+	owner, repo := gh.ownerAndRepo()
+	unresolved := gh.unresolvedCount()
+	prs := gh.pullRequests(nums, false)
+	// unresolved pull requests may belong to any of the weeks
+	errs := gh.unresolvedSince(unresolved)
+	var ret []*digestWeek
+	for _, w := range groupByWeek(prs, since) {
+		w.Section = gh.finishSection(Section{
+			PullRequests: w.Section.PullRequests,
+			ToRevision:   w.Week,
+			ChangedAt:    w.End,
+			Owner:        owner,
+			Repo:         repo,
+			WebURL:       gh.getWebURL(),
+			Degraded:     gh.offline,
+			Incomplete:   len(errs) > 0,
+			Errors:       errs,
+		})
+		ret = append(ret, w)
+	}
+	return ret
+}
+
+// groupByWeek buckets pull requests merged since by the weeks of their merge
+// dates in the location of since, newest week first
+func groupByWeek(prs []*PullRequest, since time.Time) []*digestWeek {
+	idx := make(map[string]*digestWeek)
+	var weeks []*digestWeek
+	for _, pr := range prs {
+		if pr.MergedAt == nil || pr.MergedAt.Before(since) {
+			continue
+		}
+		w := newDigestWeek(weekStart(pr.MergedAt.In(since.Location())))
+		if found, ok := idx[w.Week]; ok {
+			w = found
+		} else {
+			idx[w.Week] = w
+			weeks = append(weeks, w)
+		}
+		w.Section.PullRequests = append(w.Section.PullRequests, pr)
+	}
+	sort.Slice(weeks, func(i, j int) bool {
+		return weeks[i].Start.After(weeks[j].Start)
+	})
+	for _, w := range weeks {
+		prs := w.Section.PullRequests
+		sort.SliceStable(prs, func(i, j int) bool {
+			return prs[i].MergedAt.Before(*prs[j].MergedAt)
+		})
+	}
+	return weeks
+}
+
+// digest renders each week by the "week" heading followed by its section
+// without the version heading
+func (r *renderer) digest(weeks []*digestWeek) (string, error) {
+	var parts []string
+	for _, w := range weeks {
+		var b bytes.Buffer
+		if err := r.tmpl.ExecuteTemplate(&b, "week", w); err != nil {
+			return "", err
+		}
+		str, err := r.section(w.Section)
+		if err != nil {
+			return "", err
+		}
+		part := strings.TrimSpace(b.String())
+		if body := sectionBody(strings.TrimSpace(str)); body != "" {
+			part += "\n\n" + body
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
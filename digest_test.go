@@ -0,0 +1,87 @@
+package ghch
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Songmu/ghch/ghchtest"
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestWeekStart(t *testing.T) {
+	for in, want := range map[string]string{
+		"2024-06-05T15:04:05Z": "2024-06-03", // Wednesday
+		"2024-06-03T00:00:00Z": "2024-06-03", // Monday
+		"2024-06-09T23:59:59Z": "2024-06-03", // Sunday
+	} {
+		tm, _ := time.Parse(time.RFC3339, in)
+		if got := weekStart(tm).Format("2006-01-02"); got != want {
+			t.Errorf("weekStart(%s) = %s, want %s", in, got, want)
+		}
+	}
+	if w := newDigestWeek(time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)); w.Week != "2025-W01" {
+		t.Errorf("ISO weeks can start in the previous year: %s", w.Week)
+	}
+}
+
+func TestGroupByWeek(t *testing.T) {
+	pr := func(num int, merged string) *PullRequest {
+		p := &PullRequest{PullRequest: &octokit.PullRequest{Number: num, Title: "Change", User: octokit.User{Login: "Songmu"}}}
+		if merged != "" {
+			tm, _ := time.Parse(time.RFC3339, merged)
+			p.MergedAt = &tm
+		}
+		return p
+	}
+	since := time.Date(2024, 5, 27, 0, 0, 0, 0, time.UTC)
+	weeks := groupByWeek([]*PullRequest{
+		pr(1, "2024-06-04T10:00:00Z"),
+		pr(2, "2024-05-28T10:00:00Z"),
+		pr(3, "2024-06-03T09:00:00Z"),
+		pr(4, "2024-05-20T10:00:00Z"), // before since
+		pr(5, ""),                     // closed without being merged
+	}, since)
+	var got []string
+	for _, w := range weeks {
+		var nums []string
+		for _, p := range w.Section.PullRequests {
+			nums = append(nums, strconv.Itoa(p.Number))
+		}
+		got = append(got, w.Week+":"+strings.Join(nums, ","))
+	}
+	if expect := []string{"2024-W23:3,1", "2024-W22:2"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, want %v", got, expect)
+	}
+
+	w := weeks[0]
+	w.Section.Owner, w.Section.Repo = "o", "r"
+	str, err := (&renderer{tmpl: mdTmpl}).digest(weeks[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(str, "## 2024-W23 (Jun 3 - Jun 9)\n\n* Change [#3]") {
+		t.Errorf("got:\n%s", str)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	srv := ghchtest.NewServer()
+	defer srv.Close()
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	merged := repo.MergePR(1, "alice", "Add a feature")
+	srv.AddPullRequest("Songmu", "ghch", merged)
+	srv.AddPullRequest("Songmu", "ghch", repo.SquashPR(2, "bob", "Fix a crash"))
+
+	gh := (&ghch{repoPath: repo.Dir, gitPath: "git", apiURL: srv.APIURL(), scanRefs: true, config: &config{}}).initialize()
+	weeks := gh.digest(2, time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC))
+	if len(weeks) != 1 || len(weeks[0].Section.PullRequests) != 2 {
+		t.Fatalf("got %+v, expect a week of 2 pull requests", weeks)
+	}
+	// pull requests are cached by their merge commits
+	if got := gh.prCommits[1]; got != merged.MergeCommitSHA {
+		t.Errorf("merge commit of #1: got %q, expect %q", got, merged.MergeCommitSHA)
+	}
+}
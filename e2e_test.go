@@ -0,0 +1,163 @@
+package ghch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Songmu/ghch/ghchtest"
+)
+
+// runWithFakes runs the CLI on the repository against the fake server
+func runWithFakes(t *testing.T, srv *ghchtest.Server, repo *ghchtest.Repo, args ...string) (string, int) {
+	t.Helper()
+	defer log.SetOutput(os.Stderr)
+	var out, errOut bytes.Buffer
+	// after the subcommand, if any
+	argv := append(args, "--repo", repo.Dir, "--api-url", srv.APIURL(), "--token", "dummy")
+	code := (&CLI{OutStream: &out, ErrStream: &errOut}).Run(argv)
+	if errOut.Len() > 0 {
+		t.Log(errOut.String())
+	}
+	return out.String(), code
+}
+
+func TestEndToEnd(t *testing.T) {
+	srv := ghchtest.NewServer()
+	defer srv.Close()
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+
+	add := srv.AddPullRequest
+	add("Songmu", "ghch", repo.MergePR(1, "alice", "Add the first feature"))
+	repo.Tag("v0.1.0")
+	fix := repo.MergePR(2, "bob", "Fix a crash")
+	fix.Labels = []string{"bug"}
+	add("Songmu", "ghch", fix)
+	add("Songmu", "ghch", repo.SquashPR(3, "carol", "Document options"))
+	repo.Tag("v0.2.0")
+	repo.Push()
+
+	conf := "rules:\n  - category: Bug Fixes\n    labels: [bug]\n"
+	if err := ioutil.WriteFile(filepath.Join(repo.Dir, ".ghch.yml"), []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, code := runWithFakes(t, srv, repo, "--all", "--refs", "--format", "markdown")
+	if code != exitCodeOK {
+		t.Fatalf("got exit code %d, expect %d", code, exitCodeOK)
+	}
+	expect := `## [](https://github.com/Songmu/ghch/releases/tag/) (2024-06-01)
+
+
+## [v0.2.0](https://github.com/Songmu/ghch/releases/tag/v0.2.0) (2024-06-01)
+
+### Bug Fixes
+
+* Fix a crash [#2](https://github.com/Songmu/ghch/pull/2) ([bob](https://github.com/bob))
+
+### Other Changes
+
+* Document options [#3](https://github.com/Songmu/ghch/pull/3) ([carol](https://github.com/carol))
+
+## [v0.1.0](https://github.com/Songmu/ghch/releases/tag/v0.1.0) (2024-06-01)
+
+### Other Changes
+
+* Add the first feature [#1](https://github.com/Songmu/ghch/pull/1) ([alice](https://github.com/alice))
+`
+	if got != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", got, expect)
+	}
+
+	if _, code := runWithFakes(t, srv, repo, "release"); code != exitCodeOK {
+		t.Fatalf("got exit code %d of release, expect %d", code, exitCodeOK)
+	}
+	rels := srv.Releases("Songmu", "ghch")
+	if len(rels) != 1 || rels[0].TagName != "v0.2.0" || !strings.Contains(rels[0].Body, "Fix a crash") {
+		t.Errorf("unexpected releases: %+v", rels)
+	}
+}
+
+func TestEndToEndPartial(t *testing.T) {
+	srv := ghchtest.NewServer()
+	defer srv.Close()
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	srv.AddPullRequest("Songmu", "ghch", repo.MergePR(1, "alice", "Add a feature"))
+	// not served, such as one of another repository
+	repo.MergePR(2, "bob", "Fix a crash")
+
+	got, code := runWithFakes(t, srv, repo)
+	if code != exitCodePartial {
+		t.Errorf("got exit code %d, expect %d", code, exitCodePartial)
+	}
+	var s Section
+	if err := json.Unmarshal([]byte(got), &s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.PullRequests) != 1 || !s.Incomplete || len(s.Errors) != 1 {
+		t.Errorf("unexpected section: %+v", s)
+	}
+}
+
+func TestEndToEndUpstream(t *testing.T) {
+	srv := ghchtest.NewServer()
+	defer srv.Close()
+	repo := ghchtest.NewRepo(t, "alice", "ghch")
+	srv.AddFork("alice", "ghch", "Songmu", "ghch")
+	srv.AddPullRequest("Songmu", "ghch", repo.MergePR(1, "bob", "Fix a crash"))
+
+	got, code := runWithFakes(t, srv, repo, "--upstream", "--format", "markdown")
+	if code != exitCodeOK {
+		t.Fatalf("got exit code %d, expect %d", code, exitCodeOK)
+	}
+	if !strings.Contains(got, "* Fix a crash [#1](https://github.com/Songmu/ghch/pull/1)") {
+		t.Errorf("pull requests should be those of the upstream: %s", got)
+	}
+}
+
+func TestEndToEndNotesDiff(t *testing.T) {
+	srv := ghchtest.NewServer()
+	defer srv.Close()
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	srv.AddPullRequest("Songmu", "ghch", repo.MergePR(1, "alice", "Add a feature"))
+
+	srv.SetNotes("Songmu", "ghch", "## What's Changed\n* Add a feature by @alice in https://github.com/Songmu/ghch/pull/1\n")
+	if _, code := runWithFakes(t, srv, repo, "--github-notes", "diff"); code != exitCodeOK {
+		t.Errorf("got exit code %d, expect %d", code, exitCodeOK)
+	}
+	srv.SetNotes("Songmu", "ghch", "## What's Changed\n* Fix by @bob in https://github.com/Songmu/ghch/pull/2\n")
+	got, code := runWithFakes(t, srv, repo, "--github-notes", "diff")
+	if code != exitCodeDiffers {
+		t.Errorf("got exit code %d, expect %d", code, exitCodeDiffers)
+	}
+	if expect := "-#1 only in ghch\n+#2 only in GitHub\n"; got != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", got, expect)
+	}
+}
+
+func TestEndToEndEscapeTitles(t *testing.T) {
+	srv := ghchtest.NewServer()
+	defer srv.Close()
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	srv.AddPullRequest("Songmu", "ghch", repo.MergePR(1, "alice", "Support <details>"))
+
+	for _, tc := range []struct {
+		args   []string
+		expect string
+	}{
+		{nil, "* Support <details> [#1]"},
+		{[]string{"--escape-titles", "html"}, "* Support &lt;details&gt; [#1]"},
+	} {
+		got, code := runWithFakes(t, srv, repo, append([]string{"--format", "markdown"}, tc.args...)...)
+		if code != exitCodeOK {
+			t.Fatalf("%v: got exit code %d", tc.args, code)
+		}
+		if !strings.Contains(got, tc.expect) {
+			t.Errorf("%v: %q should be in the output: %s", tc.args, tc.expect, got)
+		}
+	}
+}
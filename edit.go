@@ -0,0 +1,85 @@
+package ghch
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func editor() string {
+	if e := os.Getenv("VISUAL"); e != "" {
+		return e
+	}
+	if e := os.Getenv("EDITOR"); e != "" {
+		return e
+	}
+	return "vi"
+}
+
+// editText lets the user edit the text with $VISUAL or $EDITOR and returns
+// the result. Emptying the text aborts the operation.
+func editText(str string) (string, error) {
+	f, err := ioutil.TempFile("", "ghch-*.md")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create file to edit")
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(str)
+	f.Close()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create file to edit")
+	}
+
+	argv := splitCommand(editor())
+	if len(argv) == 0 {
+		return "", errors.New("no editor to run. set $VISUAL or $EDITOR")
+	}
+	argv = append(argv, f.Name())
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "failed to run editor %s", argv[0])
+	}
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read edited file")
+	}
+	edited := strings.TrimRight(normalizeNewlines(string(b)), "\n")
+	if strings.TrimSpace(edited) == "" {
+		return "", errors.New("aborted due to empty notes")
+	}
+	return edited, nil
+}
+
+// splitCommand splits a command line like $EDITOR into arguments. Double
+// quotes group an argument with spaces, e.g. a path under "C:\Program Files",
+// so that it runs without a shell like `cmd /c`.
+func splitCommand(str string) (argv []string) {
+	var (
+		arg     strings.Builder
+		quoted  bool
+		started bool
+	)
+	for _, r := range str {
+		switch {
+		case r == '"':
+			quoted, started = !quoted, true
+		case !quoted && (r == ' ' || r == '\t'):
+			if started {
+				argv = append(argv, arg.String())
+				arg.Reset()
+				started = false
+			}
+		default:
+			arg.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		argv = append(argv, arg.String())
+	}
+	return argv
+}
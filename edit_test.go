@@ -0,0 +1,42 @@
+package ghch
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEditText(t *testing.T) {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if orig, ok := os.LookupEnv(env); ok {
+			defer os.Setenv(env, orig)
+		} else {
+			defer os.Unsetenv(env)
+		}
+	}
+	os.Setenv("EDITOR", "vi")
+	testCases := []struct {
+		visual string
+		expect string
+		err    bool
+	}{
+		{`sed -i "s/draft/final/"`, "## v1.0.0\n\n* final notes", false},
+		{"truncate -s 0", "", true},
+		{" ", "", true},
+		{"false", "", true},
+	}
+	for _, tc := range testCases {
+		os.Setenv("VISUAL", tc.visual)
+		got, err := editText("## v1.0.0\n\n* draft notes\n")
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q should be an error", tc.visual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tc.visual, err)
+		} else if got != tc.expect {
+			t.Errorf("%q: got %q, expect %q", tc.visual, got, tc.expect)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package ghch
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// modes of --escape-titles
+const (
+	escapeNone     = "none"
+	escapeHTML     = "html"
+	escapeMarkdown = "markdown"
+)
+
+func validTitleEscape(mode string) error {
+	switch mode {
+	case escapeNone, escapeHTML, escapeMarkdown:
+		return nil
+	}
+	return errors.Errorf("unknown --escape-titles %q: must be html, markdown or none", mode)
+}
+
+var (
+	htmlEscaper = strings.NewReplacer("<", "&lt;", ">", "&gt;")
+	// underscores are left as they don't emphasize within words like
+	// snake_case and @user_names
+	markdownEscaper = strings.NewReplacer(
+		"\\", "\\\\", "`", "\\`", "*", "\\*", "[", "\\[", "]", "\\]", "|", "\\|",
+		"<", "&lt;", ">", "&gt;",
+	)
+)
+
+// escapeTitleHTML keeps HTML tags in titles from being interpreted by
+// markdown processors
+func escapeTitleHTML(str string) string {
+	return htmlEscaper.Replace(str)
+}
+
+// escapeTitleMarkdown also escapes characters breaking inline formatting and
+// tables, so that a title is rendered as it is
+func escapeTitleMarkdown(str string) string {
+	return markdownEscaper.Replace(str)
+}
+
+var (
+	// @user and @org/team, but not e-mail addresses
+	mentionReg = regexp.MustCompile("(^|[^\\w`])(@[A-Za-z0-9][A-Za-z0-9-]*(?:/[A-Za-z0-9_.-]+)?)")
+	// #123 and owner/repo#123
+	issueRefReg = regexp.MustCompile("(^|[^\\w`&/])((?:[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)?#[0-9]+)\\b")
+)
+
+// escapeMentions wraps @mentions in code spans not to notify anyone when
+// the markdown is posted to GitHub
+func escapeMentions(str string) string {
+	return mentionReg.ReplaceAllString(str, "$1`$2`")
+}
+
+// escapeIssueRefs wraps #123 in code spans not to be linked to unrelated
+// issues of the repository the markdown is posted to
+func escapeIssueRefs(str string) string {
+	return issueRefReg.ReplaceAllString(str, "$1`$2`")
+}
+
+// mapTitles returns a copy of the section whose pull request titles and
+// commit subjects are converted by fn, leaving the original intact
+func mapTitles(rs Section, fn func(string) string) Section {
+	prs := make(map[*PullRequest]*PullRequest, len(rs.PullRequests))
+	mapPR := func(pr *PullRequest) *PullRequest {
+		if cp, ok := prs[pr]; ok {
+			return cp
+		}
+		cp := *pr
+		if pr.PullRequest != nil {
+			inner := *pr.PullRequest
+			inner.Title = fn(inner.Title)
+			cp.PullRequest = &inner
+		}
+		prs[pr] = &cp
+		return &cp
+	}
+	var pulls []*PullRequest
+	for _, pr := range rs.PullRequests {
+		pulls = append(pulls, mapPR(pr))
+	}
+	rs.PullRequests = pulls
+	var highlights []*PullRequest
+	for _, pr := range rs.Highlights {
+		highlights = append(highlights, mapPR(pr))
+	}
+	rs.Highlights = highlights
+	extras := make(map[*Extra]*Extra, len(rs.Extras))
+	mapExtras := func(es []*Extra) []*Extra {
+		var ret []*Extra
+		for _, e := range es {
+			cp, ok := extras[e]
+			if !ok {
+				c := *e
+				c.Title = fn(c.Title)
+				cp = &c
+				extras[e] = cp
+			}
+			ret = append(ret, cp)
+		}
+		return ret
+	}
+	var cats []*Category
+	for _, c := range rs.Categories {
+		cc := *c
+		cc.PullRequests = nil
+		for _, pr := range c.PullRequests {
+			cc.PullRequests = append(cc.PullRequests, mapPR(pr))
+		}
+		cc.Extras = mapExtras(c.Extras)
+		cats = append(cats, &cc)
+	}
+	rs.Categories = cats
+	rs.Extras = mapExtras(rs.Extras)
+	var commits []*Commit
+	for _, c := range rs.DirectCommits {
+		cp := *c
+		cp.Subject = fn(cp.Subject)
+		commits = append(commits, &cp)
+	}
+	rs.DirectCommits = commits
+	return rs
+}
@@ -0,0 +1,71 @@
+package ghch
+
+import (
+	"testing"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestEscapeMentions(t *testing.T) {
+	testCases := []struct {
+		input, expect string
+	}{
+		{"Thanks @Songmu for the review", "Thanks `@Songmu` for the review"},
+		{"@mackerelio/agent-team should know", "`@mackerelio/agent-team` should know"},
+		{"Mail to foo@example.com", "Mail to foo@example.com"},
+		{"Already `@escaped`", "Already `@escaped`"},
+	}
+	for _, tc := range testCases {
+		if got := escapeMentions(tc.input); got != tc.expect {
+			t.Errorf("got %q, expect %q", got, tc.expect)
+		}
+	}
+}
+
+func TestEscapeIssueRefs(t *testing.T) {
+	testCases := []struct {
+		input, expect string
+	}{
+		{"Fix #123", "Fix `#123`"},
+		{"Port Songmu/ghch#12", "Port `Songmu/ghch#12`"},
+		{"Use &#39; and https://example.com/#12", "Use &#39; and https://example.com/#12"},
+		{"C# is not a reference", "C# is not a reference"},
+	}
+	for _, tc := range testCases {
+		if got := escapeIssueRefs(tc.input); got != tc.expect {
+			t.Errorf("got %q, expect %q", got, tc.expect)
+		}
+	}
+}
+
+func TestMapTitles(t *testing.T) {
+	pr := &PullRequest{PullRequest: &octokit.PullRequest{Number: 1, Title: "Thanks @foo"}}
+	s := Section{
+		PullRequests:  []*PullRequest{pr},
+		Categories:    []*Category{{Name: "Features", PullRequests: []*PullRequest{pr}}},
+		DirectCommits: []*Commit{{SHA: "aaa", Subject: "ping @bar"}},
+	}
+	got := mapTitles(s, escapeMentions)
+	if got.PullRequests[0].Title != "Thanks `@foo`" || got.DirectCommits[0].Subject != "ping `@bar`" {
+		t.Errorf("titles should be escaped: %q, %q", got.PullRequests[0].Title, got.DirectCommits[0].Subject)
+	}
+	if got.Categories[0].PullRequests[0] != got.PullRequests[0] {
+		t.Error("categories should share the escaped pull requests")
+	}
+	if pr.Title != "Thanks @foo" || s.DirectCommits[0].Subject != "ping @bar" {
+		t.Error("the original section should be intact")
+	}
+}
+
+func TestEscapeTitles(t *testing.T) {
+	title := "Fix <script> in `foo|bar` and *[docs]* of snake_case"
+	if got, expect := escapeTitleHTML(title), "Fix &lt;script&gt; in `foo|bar` and *[docs]* of snake_case"; got != expect {
+		t.Errorf("got %q, expect %q", got, expect)
+	}
+	if got, expect := escapeTitleMarkdown(title), "Fix &lt;script&gt; in \\`foo\\|bar\\` and \\*\\[docs\\]\\* of snake_case"; got != expect {
+		t.Errorf("got %q, expect %q", got, expect)
+	}
+	if err := validTitleEscape("latex"); err == nil {
+		t.Error("unknown mode should be an error")
+	}
+}
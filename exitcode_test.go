@@ -0,0 +1,27 @@
+package ghch
+
+import (
+	"testing"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestChangelogIsEmpty(t *testing.T) {
+	pr := &PullRequest{PullRequest: &octokit.PullRequest{Number: 1}}
+	testCases := []struct {
+		name     string
+		sections []Section
+		expect   bool
+	}{
+		{"no sections", nil, true},
+		{"empty sections", []Section{{ToRevision: "v0.2.0"}, {ToRevision: "v0.1.0"}}, true},
+		{"pull requests", []Section{{}, {PullRequests: []*PullRequest{pr}}}, false},
+		{"direct commits", []Section{{DirectCommits: []*Commit{{SHA: "abc1234"}}}}, false},
+	}
+	for _, tc := range testCases {
+		chlog := Changelog{Sections: tc.sections}
+		if got := chlog.isEmpty(); got != tc.expect {
+			t.Errorf("%s: isEmpty() = %v, expect %v", tc.name, got, tc.expect)
+		}
+	}
+}
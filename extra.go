@@ -0,0 +1,121 @@
+package ghch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Extra is a hand-written entry merged into a section, for changes which
+// did not come through pull requests
+type Extra struct {
+	// version of the section, or "unreleased" for the changes since the latest version
+	Version  string `yaml:"version" json:"-"`
+	Category string `yaml:"category" json:"category,omitempty"`
+	Title    string `yaml:"title" json:"title"`
+	Author   string `yaml:"author" json:"author,omitempty"`
+	URL      string `yaml:"url" json:"url,omitempty"`
+}
+
+// extraItem is passed to the "extra" template
+type extraItem struct {
+	*Extra
+	Section Section
+}
+
+func validExtras(extras []*Extra) error {
+	for i, e := range extras {
+		if e.Title == "" {
+			return errors.Errorf("extras[%d]: title is required", i)
+		}
+	}
+	return nil
+}
+
+func isUnreleased(version string) bool {
+	return version == "" || strings.EqualFold(version, "unreleased")
+}
+
+// extrasOf returns the extras of the section up to the version from the
+// config and from <version>.yml in the extras_dir
+func (gh *ghch) extrasOf(version string) ([]*Extra, error) {
+	var extras []*Extra
+	for _, e := range gh.config.Extras {
+		if e.Version == version || isUnreleased(e.Version) && isUnreleased(version) {
+			extras = append(extras, e)
+		}
+	}
+	if gh.config.ExtrasDir == "" {
+		return extras, nil
+	}
+	name := version
+	if isUnreleased(version) {
+		name = "unreleased"
+	}
+	file := filepath.Join(gh.repoFile(gh.config.ExtrasDir), name+".yml")
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return extras, nil
+		}
+		return extras, errors.Wrap(err, "failed to read extras")
+	}
+	var sidecar []*Extra
+	if err := yaml.Unmarshal(b, &sidecar); err != nil {
+		return extras, errors.Wrapf(err, "failed to parse %s", file)
+	}
+	if err := validExtras(sidecar); err != nil {
+		return extras, errors.Wrapf(err, "invalid extras %s", file)
+	}
+	return append(extras, sidecar...), nil
+}
+
+// addExtras merges extras into the section, under their categories when the
+// pull requests are categorized
+func (gh *ghch) addExtras(s *Section, extras []*Extra) {
+	s.Extras = append(s.Extras, extras...)
+	if s.Categories == nil {
+		return
+	}
+	idx := make(map[string]*Category)
+	for _, c := range s.Categories {
+		idx[c.Name] = c
+	}
+	for _, e := range extras {
+		name := e.Category
+		if name == "" {
+			name = gh.config.defaultCategoryName()
+		}
+		c, ok := idx[name]
+		if !ok {
+			c = &Category{Name: name}
+			idx[name] = c
+		}
+		c.Extras = append(c.Extras, e)
+	}
+	// categories only with extras are placed in the order of the rules
+	var cats []*Category
+	for _, name := range gh.config.categoryOrder() {
+		if c, ok := idx[name]; ok {
+			cats = append(cats, c)
+			delete(idx, name)
+		}
+	}
+	for _, c := range s.Categories {
+		if _, ok := idx[c.Name]; ok {
+			cats = append(cats, c)
+			delete(idx, c.Name)
+		}
+	}
+	for _, e := range extras {
+		if c, ok := idx[e.Category]; ok {
+			cats = append(cats, c)
+			delete(idx, e.Category)
+		}
+	}
+	s.Categories = cats
+}
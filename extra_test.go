@@ -0,0 +1,101 @@
+package ghch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestAddExtras(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghch-extras")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "extras"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "extras", "v1.0.0.yml"), []byte(`- category: Infrastructure
+  title: Moved CI to GitHub Actions
+  author: Songmu
+`), 0644)
+	conf, err := parseConfig([]byte(`rules:
+  - category: Features
+    labels: [feature]
+  - category: Infrastructure
+    labels: [infra]
+extras_dir: extras
+extras:
+  - version: v1.0.0
+    title: Published the docs site
+    url: https://example.com/docs
+  - version: unreleased
+    title: Not yet
+`), defaultConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gh := &ghch{repoPath: dir, config: conf}
+	extras, err := gh.extrasOf("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(extras) != 2 {
+		t.Fatalf("extras of the config and the sidecar should be merged: %v", extras)
+	}
+
+	pr := &PullRequest{PullRequest: &octokit.PullRequest{Number: 1, Title: "Add --all", User: octokit.User{Login: "Songmu"}}, Category: "Features"}
+	s := Section{ToRevision: "v1.0.0", Owner: "o", Repo: "r", PullRequests: []*PullRequest{pr}}
+	s.Categories = groupByCategory(s.PullRequests, conf.categoryOrder())
+	gh.addExtras(&s, extras)
+	var names []string
+	for _, c := range s.Categories {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "Features,Infrastructure,Other Changes" {
+		t.Errorf("categories: got %s", got)
+	}
+
+	str, err := (&renderer{tmpl: mdTmpl}).section(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `
+
+### Infrastructure
+
+* Moved CI to GitHub Actions ([Songmu](https://github.com/Songmu))
+
+### Other Changes
+
+* [Published the docs site](https://example.com/docs)`
+	if !strings.HasSuffix(str, expect) {
+		t.Errorf("got:\n%s", str)
+	}
+	if s.isEmpty() {
+		t.Error("a section with extras should not be empty")
+	}
+}
+
+func TestRenderExtraAuthors(t *testing.T) {
+	r := &renderer{tmpl: mdTmpl}
+	for _, tc := range []struct {
+		author string
+		expect string
+	}{
+		{"Songmu", "* Moved CI ([Songmu](https://github.com/Songmu))"},
+		{"Jane Doe", "* Moved CI (Jane Doe)"},
+		{"", "* Moved CI"},
+	} {
+		s := Section{ToRevision: "v1.0.0", Owner: "o", Repo: "r", Extras: []*Extra{{Title: "Moved CI", Author: tc.author}}}
+		str, err := r.section(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(str, "\n"+tc.expect) {
+			t.Errorf("author %q: got:\n%s", tc.author, str)
+		}
+	}
+}
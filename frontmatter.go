@@ -0,0 +1,54 @@
+package ghch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	frontMatterSection  = "section"
+	frontMatterDocument = "document"
+)
+
+func validFrontMatter(fm string) error {
+	switch fm {
+	case "", frontMatterSection, frontMatterDocument:
+		return nil
+	}
+	return errors.Errorf("invalid --front-matter %q: must be %q or %q", fm, frontMatterSection, frontMatterDocument)
+}
+
+var slugReg = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slugify(s string) string {
+	return strings.Trim(slugReg.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// frontMatter renders YAML front matter understood by both Hugo and Jekyll
+func frontMatter(title, version string, date time.Time, slug string) string {
+	return fmt.Sprintf("---\ntitle: %q\ndate: %s\nversion: %q\nslug: %q\n---\n\n",
+		title, date.Format(time.RFC3339), version, slug)
+}
+
+func sectionFrontMatter(rs Section) string {
+	ver := rs.ToRevision
+	if ver == "" {
+		ver = "Unreleased"
+	}
+	return frontMatter(ver, rs.ToRevision, rs.ChangedAt, slugify(ver))
+}
+
+func changelogFrontMatter(chlog Changelog) string {
+	var (
+		ver  string
+		date time.Time
+	)
+	if len(chlog.Sections) > 0 {
+		ver, date = chlog.Sections[0].ToRevision, chlog.Sections[0].ChangedAt
+	}
+	return frontMatter("Changelog", ver, date, "changelog")
+}
@@ -0,0 +1,60 @@
+package ghch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlugify(t *testing.T) {
+	testCases := []struct {
+		input, expect string
+	}{
+		{"v1.2.3", "v1-2-3"},
+		{"Unreleased", "unreleased"},
+		{"release/2024_06 (hotfix)", "release-2024-06-hotfix"},
+		{"--v1--", "v1"},
+	}
+	for _, tc := range testCases {
+		if got := slugify(tc.input); got != tc.expect {
+			t.Errorf("slugify(%q): got %q, expect %q", tc.input, got, tc.expect)
+		}
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	at := time.Date(2016, 4, 21, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name   string
+		got    string
+		expect string
+	}{
+		{
+			"section",
+			sectionFrontMatter(Section{ToRevision: "v0.30.2", ChangedAt: at}),
+			"---\ntitle: \"v0.30.2\"\ndate: 2016-04-21T10:00:00Z\nversion: \"v0.30.2\"\nslug: \"v0-30-2\"\n---\n\n",
+		},
+		{
+			"unreleased section",
+			sectionFrontMatter(Section{ChangedAt: at}),
+			"---\ntitle: \"Unreleased\"\ndate: 2016-04-21T10:00:00Z\nversion: \"\"\nslug: \"unreleased\"\n---\n\n",
+		},
+		{
+			"document",
+			changelogFrontMatter(Changelog{Sections: []Section{{ToRevision: "v0.30.2", ChangedAt: at}, {ToRevision: "v0.30.1"}}}),
+			"---\ntitle: \"Changelog\"\ndate: 2016-04-21T10:00:00Z\nversion: \"v0.30.2\"\nslug: \"changelog\"\n---\n\n",
+		},
+	}
+	for _, tc := range testCases {
+		if tc.got != tc.expect {
+			t.Errorf("%s: got %q, expect %q", tc.name, tc.got, tc.expect)
+		}
+	}
+	for _, fm := range []string{"", "section", "document"} {
+		if err := validFrontMatter(fm); err != nil {
+			t.Errorf("%q should be valid: %s", fm, err)
+		}
+	}
+	if err := validFrontMatter("toml"); err == nil {
+		t.Error("unknown --front-matter should be invalid")
+	}
+}
@@ -3,10 +3,14 @@ package ghch
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,20 +26,130 @@ const version = "0.0.1"
 
 type ghch struct {
 	repoPath string
+	gitDir   string
+	workTree string
 	gitPath  string
 	remote   string
+	branch   string
 	verbose  bool
 	token    string
+	apiURL   string
+	webURL   string
 	client   *octokit.Client
+	config   *config
+	// counts API calls of the run, logged with showAPIStats
+	apiStats     *apiStats
+	httpClient   *http.Client
+	showAPIStats bool
+
+	scanRefs      bool
+	verifyRefs    bool
+	directCommits bool
+	backports     bool
+	stats         bool
+	compare       bool
+	cacheDir      string
+	reverts       string
+	limit         int
+	milestone     string
+	versionsFrom  string
+	scheme        versionScheme
+	githubNotes   string
+	sizes         bool
+	excludePRs    map[int]bool
+	reactions     bool
+	maxPRs        int
+	// pull requests are built from commits without the API
+	offline bool
+	// a fork of the remote resolves to its parent by the API
+	upstream bool
+
+	// lazily loaded by tagRefs and ownerAndRepo
+	tags      map[string]tagRef
+	tagsOnce  sync.Once
+	owner     string
+	repo      string
+	ownerOnce sync.Once
+	// GitHub Releases by their tags, with --versions-from=releases
+	releases     map[string]*releaseRef
+	releasesOnce sync.Once
+	// commits of releases whose tags are not fetched, by localRevision
+	releaseCommits map[string]string
+
+	// merge commits of pull request numbers found in the history
+	prCommits map[int]string
+
+	// merge and squash commits by pull request numbers, when offline
+	offlineCommits map[int]*offlineCommit
+
+	// answers confirmations of ranges over maxPRs, nil on non-terminals
+	prompt io.Reader
+	// ranges over maxPRs have been confirmed
+	confirmedMax bool
+
+	// why pull requests could not be fetched, in the order they failed
+	unresolvedMu sync.Mutex
+	unresolved   []string
+	failed       bool
+}
+
+func (gh *ghch) fail(err error) {
+	log.Print(err)
+	gh.failed = true
+}
+
+// unresolve logs err of a pull request left out of or incomplete in the
+// output, which makes the section incomplete and the run partial
+func (gh *ghch) unresolve(err error) {
+	log.Print(err)
+	gh.unresolvedMu.Lock()
+	defer gh.unresolvedMu.Unlock()
+	gh.unresolved = append(gh.unresolved, err.Error())
+}
+
+// unresolvedSince returns the errors of unresolve after the first n
+func (gh *ghch) unresolvedSince(n int) []string {
+	gh.unresolvedMu.Lock()
+	defer gh.unresolvedMu.Unlock()
+	if len(gh.unresolved) <= n {
+		return nil
+	}
+	return append([]string{}, gh.unresolved[n:]...)
+}
+
+func (gh *ghch) unresolvedCount() int {
+	gh.unresolvedMu.Lock()
+	defer gh.unresolvedMu.Unlock()
+	return len(gh.unresolved)
+}
+
+// exitCode reports hard errors and unresolved pull requests of the run
+func (gh *ghch) exitCode(empty, noChangesCode bool) int {
+	gh.reportAPIStats()
+	switch {
+	case gh.failed:
+		return exitCodeErr
+	case gh.unresolvedCount() > 0:
+		return exitCodePartial
+	case noChangesCode && empty:
+		return exitCodeNoChanges
+	}
+	return exitCodeOK
 }
 
 func (gh *ghch) initialize() *ghch {
+	gh.setGitDir()
 	var auth octokit.AuthMethod
 	gh.setToken()
 	if gh.token != "" {
 		auth = octokit.TokenAuth{AccessToken: gh.token}
 	}
-	gh.client = octokit.NewClient(auth)
+	gh.apiStats = &apiStats{}
+	gh.httpClient = newAPIClient(gh.apiStats)
+	gh.client = octokit.NewClientWith(gh.getAPIURL(), "ghch/"+version, auth, gh.httpClient)
+	if gh.config == nil {
+		gh.config = &config{}
+	}
 	return gh
 }
 
@@ -50,34 +164,192 @@ func (gh *ghch) setToken() {
 	return
 }
 
+// setGitDir takes over $GIT_DIR and $GIT_WORK_TREE, and makes the paths
+// absolute so that they don't depend on the directory git runs in
+func (gh *ghch) setGitDir() {
+	if gh.gitDir == "" {
+		gh.gitDir = os.Getenv("GIT_DIR")
+	}
+	if gh.workTree == "" {
+		gh.workTree = os.Getenv("GIT_WORK_TREE")
+	}
+	for _, p := range []*string{&gh.gitDir, &gh.workTree} {
+		if *p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(*p); err == nil {
+			*p = abs
+		}
+	}
+}
+
+// gitEnv is the environment of the commands ghch runs, with the absolute
+// $GIT_DIR and $GIT_WORK_TREE in place of those given, which may be relative
+func (gh *ghch) gitEnv() []string {
+	env := os.Environ()
+	if gh.gitDir != "" {
+		env = append(env, "GIT_DIR="+gh.gitDir)
+	}
+	if gh.workTree != "" {
+		env = append(env, "GIT_WORK_TREE="+gh.workTree)
+	}
+	return env
+}
+
+func (gh *ghch) gitArgs() []string {
+	// output non-ASCII paths as they are instead of quoting them
+	arg := []string{"-c", "core.quotePath=false"}
+	if gh.gitDir == "" {
+		return append(arg, "-C", gh.repoPath)
+	}
+	arg = append(arg, "--git-dir", gh.gitDir)
+	if gh.workTree != "" {
+		arg = append(arg, "--work-tree", gh.workTree)
+	}
+	return arg
+}
+
+func (gh *ghch) isBare() bool {
+	out, _ := gh.cmd("rev-parse", "--is-bare-repository")
+	return strings.TrimSpace(out) == "true"
+}
+
+// workDir is the directory files like the config are looked up in
+func (gh *ghch) workDir() string {
+	if gh.workTree != "" {
+		return gh.workTree
+	}
+	if gh.gitDir == "" {
+		return gh.repoPath
+	}
+	if gh.isBare() {
+		return gh.gitDir
+	}
+	if out, err := gh.cmd("rev-parse", "--show-toplevel"); err == nil {
+		return strings.TrimSpace(out)
+	}
+	return gh.repoPath
+}
+
+func (gh *ghch) repoFile(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(gh.workDir(), file)
+}
+
 func (gh *ghch) gitProg() string {
-	if gh.gitPath != "" {
-		return gh.gitPath
+	// paths with spaces are often pasted with quotes on Windows
+	if p := strings.Trim(gh.gitPath, `"'`); p != "" && p != "git" {
+		return p
+	}
+	return findGit()
+}
+
+// findGit looks up git in $PATH, and then in the default locations of Git
+// for Windows which installers may not add to $PATH
+func findGit() string {
+	if p, err := exec.LookPath("git"); err == nil || runtime.GOOS != "windows" {
+		if err != nil {
+			return "git"
+		}
+		return p
 	}
-	return "git"
+	for _, env := range []string{"ProgramFiles", "ProgramW6432", "ProgramFiles(x86)", "LOCALAPPDATA"} {
+		dir := os.Getenv(env)
+		if dir == "" {
+			continue
+		}
+		if env == "LOCALAPPDATA" {
+			dir = filepath.Join(dir, "Programs")
+		}
+		p := filepath.Join(dir, "Git", "cmd", "git.exe")
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return "git.exe"
+}
+
+// normalizeNewlines converts CRLF into LF, which git may output on Windows
+// depending on its configuration and wrappers
+func normalizeNewlines(str string) string {
+	return strings.Replace(str, "\r\n", "\n", -1)
+}
+
+// splitLines splits str into lines regardless of its newline style
+func splitLines(str string) []string {
+	return strings.Split(normalizeNewlines(str), "\n")
 }
 
 func (gh *ghch) cmd(argv ...string) (string, error) {
-	arg := []string{"-C", gh.repoPath}
-	arg = append(arg, argv...)
+	arg := append(gh.gitArgs(), argv...)
 	cmd := exec.Command(gh.gitProg(), arg...)
-	cmd.Env = append(os.Environ(), "LANG=C")
+	cmd.Env = append(gh.gitEnv(), "LANG=C")
 
 	var b bytes.Buffer
 	cmd.Stdout = &b
 	cmd.Stderr = os.Stderr
 	err := cmd.Run()
-	return b.String(), err
+	return normalizeNewlines(b.String()), err
 }
 
 var verReg = regexp.MustCompile(`^v?[0-9]+(?:\.[0-9]+){0,2}$`)
 
 func (gh *ghch) versions() []string {
-	sv := gitsemvers.Semvers{
-		RepoPath: gh.repoPath,
-		GitPath:  gh.gitPath,
+	scheme := gh.scheme
+	if scheme == nil && gh.gitDir != "" {
+		// gitsemvers runs git with $GIT_DIR as given, which may be relative
+		scheme = semverScheme{}
 	}
-	return sv.VersionStrings()
+	var vers []string
+	if gh.versionsFrom == versionsFromReleases {
+		vers = releaseVersions(gh.releaseRefs())
+	} else if scheme != nil {
+		out, err := gh.cmd("tag", "--list")
+		if err != nil {
+			log.Print(errors.Wrap(err, "failed to list tags"))
+			return nil
+		}
+		vers = schemeVersions(scheme, strings.Fields(out))
+	} else {
+		sv := gitsemvers.Semvers{
+			RepoPath: gh.repoPath,
+			GitPath:  gh.gitProg(),
+		}
+		vers = sv.VersionStrings()
+	}
+	if gh.branch == "" {
+		return vers
+	}
+	out, err := gh.cmd("tag", "--merged", gh.branch)
+	if err != nil {
+		log.Print(errors.Wrapf(err, "failed to list tags reachable from %s", gh.branch))
+		return nil
+	}
+	return filterReachableVersions(vers, out)
+}
+
+func filterReachableVersions(vers []string, tagsOut string) []string {
+	reachable := make(map[string]bool)
+	for _, t := range strings.Fields(tagsOut) {
+		reachable[t] = true
+	}
+	var ret []string
+	for _, v := range vers {
+		if reachable[v] {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// head returns the revision the changelog is generated up to by default
+func (gh *ghch) head() string {
+	if gh.branch != "" {
+		return gh.branch
+	}
+	return "HEAD"
 }
 
 func (gh *ghch) getRemote() string {
@@ -90,6 +362,16 @@ func (gh *ghch) getRemote() string {
 var repoURLReg = regexp.MustCompile(`([^/:]+)/([^/]+?)(?:\.git)?$`)
 
 func (gh *ghch) ownerAndRepo() (owner, repo string) {
+	gh.ownerOnce.Do(func() {
+		gh.owner, gh.repo = gh.detectOwnerAndRepo()
+		if gh.upstream && !gh.offline && gh.owner != "" {
+			gh.owner, gh.repo = gh.upstreamOf(gh.owner, gh.repo)
+		}
+	})
+	return gh.owner, gh.repo
+}
+
+func (gh *ghch) detectOwnerAndRepo() (owner, repo string) {
 	out, _ := gh.cmd("remote", "-v")
 	remotes := strings.Split(out, "\n")
 	for _, r := range remotes {
@@ -103,12 +385,64 @@ func (gh *ghch) ownerAndRepo() (owner, repo string) {
 	return
 }
 
-func (gh *ghch) mergedPRs(from, to string) (prs []*octokit.PullRequest) {
+// upstreamOf returns the repository owner/repo was forked from, or itself
+// when it is not a fork
+func (gh *ghch) upstreamOf(owner, repo string) (string, string) {
+	var v struct {
+		Parent *struct {
+			Name  string `json:"name"`
+			Owner struct {
+				Login string `json:"login"`
+			} `json:"owner"`
+		} `json:"parent"`
+	}
+	if _, err := gh.apiRequest("GET", "repos/"+owner+"/"+repo, nil, &v); err != nil {
+		gh.fail(errors.Wrapf(err, "failed to resolve the upstream of %s/%s", owner, repo))
+		return owner, repo
+	}
+	if v.Parent == nil {
+		return owner, repo
+	}
+	return v.Parent.Owner.Login, v.Parent.Name
+}
+
+// PullRequest is a merged pull request annotated by ghch
+type PullRequest struct {
+	*octokit.PullRequest
+	Labels   []string  `json:"labels,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Backport *Backport `json:"backport,omitempty"`
+	// named by the lines changed, with --sizes
+	Size string `json:"size,omitempty"`
+	// given by the components of the config
+	Component string `json:"component,omitempty"`
+	// picked by the highlights of the config
+	Highlight bool `json:"highlight,omitempty"`
+	// title before the translation of the config
+	OriginalTitle string `json:"original_title,omitempty"`
+	// the git author with --offline, when the login is unknown
+	Author string `json:"author,omitempty"`
+	// 👍 reactions and comments, with --reactions
+	ThumbsUp int `json:"thumbs_up,omitempty"`
+	Comments int `json:"comments,omitempty"`
+	// numbers of pull requests reverting this one and reverted by this one
+	RevertedBy int `json:"reverted_by,omitempty"`
+	Reverts    int `json:"reverts,omitempty"`
+
+	// changed files, fetched only when rules refer to paths
+	Files []string `json:"-"`
+}
+
+// pullRequests fetches the pull requests of nums except excluded ones. Those
+// of released sections are served from the cache when possible.
+func (gh *ghch) pullRequests(nums []int, released bool) (prs []*PullRequest) {
+	if gh.offline {
+		return gh.offlinePullRequests(nums, released)
+	}
 	owner, repo := gh.ownerAndRepo()
-	nums := gh.mergedPRNums(from, to)
 
 	var wg sync.WaitGroup
-	prCh := make(chan *octokit.PullRequest)
+	prCh := make(chan *PullRequest)
 	finish := make(chan struct{})
 
 	go func() {
@@ -123,19 +457,15 @@ func (gh *ghch) mergedPRs(from, to string) (prs []*octokit.PullRequest) {
 	}()
 
 	for _, num := range nums {
+		if gh.excludePRs[num] {
+			continue
+		}
 		wg.Add(1)
 		go func(num int) {
 			defer wg.Done()
-			url, _ := octokit.PullRequestsURL.Expand(octokit.M{"owner": owner, "repo": repo, "number": num})
-			pr, r := gh.client.PullRequests(url).One()
-			if r.HasError() {
-				log.Print(r.Err)
-				return
-			}
-			if !gh.verbose {
-				pr = reducePR(pr)
+			if pr := gh.pullRequest(owner, repo, num, released); pr != nil {
+				prCh <- pr
 			}
-			prCh <- pr
 		}(num)
 	}
 	wg.Wait()
@@ -145,6 +475,95 @@ func (gh *ghch) mergedPRs(from, to string) (prs []*octokit.PullRequest) {
 	return
 }
 
+func (gh *ghch) pullRequest(owner, repo string, num int, released bool) *PullRequest {
+	var p *PullRequest
+	if released {
+		p = gh.loadCachedPR(owner, repo, num)
+	}
+	cached := p != nil
+	if !cached {
+		url, _ := octokit.PullRequestsURL.Expand(octokit.M{"owner": owner, "repo": repo, "number": num})
+		pr, r := gh.client.PullRequests(url).One()
+		if r.HasError() {
+			// a reference which turns out not to be a pull request is not a failure
+			if rerr, ok := r.Err.(*octokit.ResponseError); ok && gh.verifyRefs && rerr.Type == octokit.ErrorNotFound {
+				return nil
+			}
+			gh.unresolve(errors.Wrapf(r.Err, "failed to fetch #%d", num))
+			return nil
+		}
+		p = &PullRequest{PullRequest: pr}
+	}
+	if gh.verifyRefs && p.MergedAt == nil {
+		return nil
+	}
+	// rewrite the cached entry only when something was fetched
+	dirty := !cached || gh.config.needsLabels() && p.Labels == nil || gh.config.needsFiles() && p.Files == nil
+	if err := gh.enrichPR(owner, repo, p); err != nil {
+		gh.unresolve(err)
+	} else if dirty && p.MergedAt != nil {
+		gh.storeCachedPR(owner, repo, p)
+	}
+	if gh.reactions || gh.config.needsReactions() {
+		if err := gh.fetchReactions(owner, repo, p); err != nil {
+			gh.unresolve(err)
+		}
+	} else if gh.verbose {
+		// the field shadows the one of the full pull request
+		p.Comments = p.PullRequest.Comments
+	}
+	if gh.sizes {
+		p.Size = sizeOf(gh.config.Sizes, p.Additions+p.Deletions)
+	}
+	if !gh.verbose {
+		full := p.PullRequest
+		p.PullRequest = reducePR(full)
+		if gh.sizes {
+			p.Additions, p.Deletions, p.ChangedFiles = full.Additions, full.Deletions, full.ChangedFiles
+		}
+	}
+	return p
+}
+
+// enrichPR fetches labels and files which rules need, unless they are cached
+func (gh *ghch) enrichPR(owner, repo string, pr *PullRequest) error {
+	if gh.config.needsLabels() && pr.Labels == nil {
+		url, _ := octokit.RepoIssuesURL.Expand(octokit.M{"owner": owner, "repo": repo, "number": pr.Number})
+		issue, r := gh.client.Issues(url).One()
+		if r.HasError() {
+			return errors.Wrapf(r.Err, "failed to fetch labels of #%d", pr.Number)
+		}
+		pr.Labels = []string{}
+		for _, l := range issue.Labels {
+			pr.Labels = append(pr.Labels, l.Name)
+		}
+	}
+	if gh.config.needsFiles() && pr.Files == nil {
+		files, err := gh.getPRFiles(owner, repo, pr.Number)
+		if err != nil {
+			return err
+		}
+		pr.Files = append([]string{}, files...)
+	}
+	return nil
+}
+
+func (gh *ghch) getPRFiles(owner, repo string, num int) (files []string, err error) {
+	path := fmt.Sprintf("repos/%s/%s/pulls/%d/files?per_page=100", owner, repo, num)
+	for path != "" {
+		var page []struct {
+			Filename string `json:"filename"`
+		}
+		if path, err = gh.apiGet(path, &page); err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch files of #%d", num)
+		}
+		for _, f := range page {
+			files = append(files, f.Filename)
+		}
+	}
+	return files, nil
+}
+
 func (gh *ghch) getLatestSemverTag() string {
 	vers := gh.versions()
 	if len(vers) < 1 {
@@ -153,36 +572,161 @@ func (gh *ghch) getLatestSemverTag() string {
 	return vers[0]
 }
 
-var prMergeReg = regexp.MustCompile(`^[a-f0-9]{7} Merge pull request #([0-9]+) from`)
+var prMergeSubjectReg = regexp.MustCompile(`^Merge pull request #([0-9]+) from`)
 
-func (gh *ghch) mergedPRNums(from, to string) (nums []int) {
+func (gh *ghch) revisionRange(from, to string) string {
+	from, to = gh.localRevision(from), gh.localRevision(to)
+	if to == "" {
+		to = gh.head()
+	}
 	if from == "" {
-		from, _ = gh.cmd("rev-list", "--max-parents=0", "HEAD")
+		from, _ = gh.cmd("rev-list", "--max-parents=0", to)
 		from = strings.TrimSpace(from)
 	}
+	return fmt.Sprintf("%s..%s", from, to)
+}
+
+func (gh *ghch) mergedPRNums(from, to string) (nums []int) {
+	if gh.compare {
+		nums, err := gh.comparePRNums(from, to)
+		if err != nil {
+			gh.fail(err)
+		}
+		return nums
+	}
+	revisionRange := gh.revisionRange(from, to)
+	out, err := gh.cmd("log", revisionRange, "--merges", "--format=%x1e%H%x00%s")
+	if err != nil {
+		gh.fail(errors.Wrap(err, "failed to list merged pull requests. `git log` failed"))
+		return
+	}
+	nums = gh.recordPRCommits(out, parseMergeSubject)
+	if gh.offline {
+		nums = appendUniqueNums(nums, gh.squashPRNums(revisionRange)...)
+	}
+	if !gh.scanRefs {
+		return
+	}
 
-	revisionRange := fmt.Sprintf("%s..%s", from, to)
-	out, err := gh.cmd("log", revisionRange, "--merges", "--oneline")
+	out, err = gh.cmd("log", revisionRange, "--format=%x1e%H%x00%B")
 	if err != nil {
 		return
 	}
-	return parseMergedPRNums(out)
+	return appendUniqueNums(nums, gh.recordPRCommits(out, parsePRRefs)...)
 }
 
-func parseMergedPRNums(out string) (nums []int) {
-	lines := strings.Split(out, "\n")
-	for _, line := range lines {
-		if matches := prMergeReg.FindStringSubmatch(line); len(matches) > 1 {
-			i, _ := strconv.Atoi(matches[1])
-			nums = append(nums, i)
+// recordPRCommits parses the pull request numbers of each "%x1e%H%x00..."
+// record of `git log` by parse, and remembers the commits of them for the
+// cache of pull requests
+func (gh *ghch) recordPRCommits(out string, parse func(string) []int) (nums []int) {
+	if gh.prCommits == nil {
+		gh.prCommits = make(map[int]string)
+	}
+	seen := make(map[int]bool)
+	for _, rec := range strings.Split(normalizeNewlines(out), "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(rec), "\x00", 2)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, num := range parse(fields[1]) {
+			if _, ok := gh.prCommits[num]; !ok {
+				gh.prCommits[num] = fields[0]
+			}
+			if !seen[num] {
+				seen[num] = true
+				nums = append(nums, num)
+			}
 		}
 	}
+	return nums
+}
+
+func parseMergeSubject(subject string) []int {
+	if matches := prMergeSubjectReg.FindStringSubmatch(subject); len(matches) > 1 {
+		i, _ := strconv.Atoi(matches[1])
+		return []int{i}
+	}
+	return nil
+}
+
+var prRefReg = regexp.MustCompile(`\(#([0-9]+)\)`)
+
+// parsePRRefs extracts PR numbers from "(#123)" style references found
+// anywhere in commit messages, such as squash merge subjects or bodies of
+// imported commits.
+func parsePRRefs(out string) (nums []int) {
+	for _, matches := range prRefReg.FindAllStringSubmatch(out, -1) {
+		i, _ := strconv.Atoi(matches[1])
+		nums = appendUniqueNums(nums, i)
+	}
+	return
+}
+
+func appendUniqueNums(nums []int, adds ...int) []int {
+	seen := make(map[int]bool, len(nums))
+	for _, n := range nums {
+		seen[n] = true
+	}
+	for _, n := range adds {
+		if !seen[n] {
+			seen[n] = true
+			nums = append(nums, n)
+		}
+	}
+	return nums
+}
+
+// Commit is a commit pushed directly to the mainline, not through a pull request
+type Commit struct {
+	SHA      string    `json:"sha"`
+	Subject  string    `json:"subject"`
+	Author   string    `json:"author"`
+	Email    string    `json:"-"` // only to look up aliases of the author
+	Backport *Backport `json:"backport,omitempty"`
+	// SHAs of the commits reverting this one and reverted by this one
+	RevertedBy string `json:"reverted_by,omitempty"`
+	Reverts    string `json:"reverts,omitempty"`
+}
+
+func (gh *ghch) getDirectCommits(from, to string) []*Commit {
+	out, err := gh.cmd("log", gh.revisionRange(from, to), "--first-parent", "--no-merges", "--format=%h%x00%aN%x00%aE%x00%s")
+	if err != nil {
+		return nil
+	}
+	return parseDirectCommits(out)
+}
+
+func parseDirectCommits(out string) (commits []*Commit) {
+	for _, line := range splitLines(out) {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		// squash merged pull requests are already listed as pull requests
+		if prRefReg.MatchString(fields[3]) {
+			continue
+		}
+		commits = append(commits, &Commit{
+			SHA:     fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Subject: fields[3],
+		})
+	}
 	return
 }
 
 func (gh *ghch) getChangedAt(rev string) (time.Time, error) {
 	if rev == "" {
-		rev = "HEAD"
+		rev = gh.head()
+	}
+	if gh.versionsFrom == versionsFromReleases {
+		if r, ok := gh.releaseRefs()[rev]; ok {
+			return r.date(), nil
+		}
+	}
+	if t, ok := gh.tagRefs()[rev]; ok {
+		return t.ChangedAt, nil
 	}
 	out, err := gh.cmd("show", "-s", rev+"^{commit}", `--format=%ct`)
 	if err != nil {
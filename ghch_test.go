@@ -1,8 +1,16 @@
 package ghch
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/Songmu/ghch/ghchtest"
+	"github.com/pkg/errors"
 )
 
 func TestParsePRNums(t *testing.T) {
@@ -76,7 +84,233 @@ ca345ea Merge pull request #195 from mackerelio/introduce-motemen-go-cli
 		192,
 		191,
 	}
-	if !reflect.DeepEqual(parseMergedPRNums(input), expect) {
+	// as listed by `git log --merges --format=%x1e%H%x00%s`
+	var records string
+	for _, line := range splitLines(input) {
+		records += "\x1e" + strings.Replace(line, " ", "\x00", 1) + "\n"
+	}
+	gh := &ghch{}
+	if !reflect.DeepEqual(gh.recordPRCommits(records, parseMergeSubject), expect) {
 		t.Errorf("somthing went wrong")
 	}
+	if gh.prCommits[225] != "6191693" {
+		t.Errorf("merge commit of #225: got %q", gh.prCommits[225])
+	}
+}
+
+func TestParsePRRefs(t *testing.T) {
+	input := `Add feature flags (#231)
+
+Imported from the old repository, originally (#12).
+
+Merge pull request #230 from mackerelio/fix
+
+* fix typo (#229)
+* see also #228, and (#231) again
+`
+	expect := []int{231, 12, 229}
+	if got := parsePRRefs(input); !reflect.DeepEqual(got, expect) {
+		t.Errorf("parsePRRefs: got %v, expect %v", got, expect)
+	}
+}
+
+func TestParseDirectCommits(t *testing.T) {
+	input := "1a2b3c4\x00Songmu\x00y@songmu.jp\x00update README\n" +
+		"5d6e7f8\x00yukiyan\x00yukiyan@example.com\x00Fix typo (#221)\n" +
+		"9a8b7c6\x00Songmu\x00y@songmu.jp\x00bump version to 0.30.3\n"
+	expect := []*Commit{
+		{SHA: "1a2b3c4", Author: "Songmu", Email: "y@songmu.jp", Subject: "update README"},
+		{SHA: "9a8b7c6", Author: "Songmu", Email: "y@songmu.jp", Subject: "bump version to 0.30.3"},
+	}
+	if got := parseDirectCommits(input); !reflect.DeepEqual(got, expect) {
+		t.Errorf("parseDirectCommits: got %v, expect %v", got, expect)
+	}
+	crlf := strings.Replace(input, "\n", "\r\n", -1)
+	if got := parseDirectCommits(crlf); !reflect.DeepEqual(got, expect) {
+		t.Errorf("parseDirectCommits with CRLF: got %v, expect %v", got, expect)
+	}
+}
+
+func TestParseCRLF(t *testing.T) {
+	input := "\x1e6191693\x00Merge pull request #225 from mackerelio/fix-test\r\n" +
+		"\x1edbb1d50\x00Merge pull request #224 from mackerelio/retry-retire\r\n"
+	if got := (&ghch{}).recordPRCommits(input, parseMergeSubject); !reflect.DeepEqual(got, []int{225, 224}) {
+		t.Errorf("recordPRCommits: got %v", got)
+	}
+	tags := parseTagRefs("v0.1.0\x00aaa\x00\x001461750000\x00\r\nv0.2.0\x00bbb\x00\x001461760000\x00\r\n")
+	if len(tags) != 2 || tags["v0.2.0"].ChangedAt.Unix() != 1461760000 {
+		t.Errorf("parseTagRefs: got %+v", tags)
+	}
+	order, commits := parseHistory("\x1em1\x00r c1\x00Merge pull request #1 from foo/bar\r\n\x1ec1\x00r\x00Add bar\r\n", false, false)
+	if len(order) != 2 || !reflect.DeepEqual(commits["m1"].nums, []int{1}) {
+		t.Errorf("parseHistory: got %v %+v", order, commits)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	testCases := []struct {
+		input  string
+		expect []string
+	}{
+		{"vim", []string{"vim"}},
+		{"code --wait", []string{"code", "--wait"}},
+		{`"C:\Program Files\Notepad++\notepad++.exe" -multiInst -nosession`, []string{`C:\Program Files\Notepad++\notepad++.exe`, "-multiInst", "-nosession"}},
+		{`emacs ""`, []string{"emacs", ""}},
+	}
+	for _, tc := range testCases {
+		if got := splitCommand(tc.input); !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("splitCommand(%q): got %q, expect %q", tc.input, got, tc.expect)
+		}
+	}
+}
+
+func TestParseBackport(t *testing.T) {
+	testCases := []struct {
+		msg    string
+		expect *Backport
+	}{
+		{"fix crash on start\n\n(cherry picked from commit 5b0a536e2ac5301)", &Backport{Commit: "5b0a536e2ac5301"}},
+		{"[Backport release/1.x] fix crash on start\n\nBackport 5b0a536 from #191.", &Backport{PullRequest: 191}},
+		{"Backport of #192 to 1.x", &Backport{PullRequest: 192}},
+		{"fix crash on start", nil},
+	}
+	for _, tc := range testCases {
+		if got := parseBackport(tc.msg); !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("parseBackport(%q): got %+v, expect %+v", tc.msg, got, tc.expect)
+		}
+	}
+}
+
+func TestFilterReachableVersions(t *testing.T) {
+	vers := []string{"v2.1.0", "v2.0.0", "v1.2.1", "v1.2.0", "v1.1.0"}
+	tagsOut := "v1.1.0\nv1.2.0\nv1.2.1\nsome-other-tag\n"
+	expect := []string{"v1.2.1", "v1.2.0", "v1.1.0"}
+	if got := filterReachableVersions(vers, tagsOut); !reflect.DeepEqual(got, expect) {
+		t.Errorf("filterReachableVersions: got %v, expect %v", got, expect)
+	}
+}
+
+func TestPullRequestsExcluded(t *testing.T) {
+	conf, err := parseConfig([]byte("exclude_pull_requests: [12, 15]\n"), defaultConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []int{12, 15}; !reflect.DeepEqual(conf.ExcludePRs, expect) {
+		t.Errorf("got %v, expect %v", conf.ExcludePRs, expect)
+	}
+	gh := &ghch{config: conf, excludePRs: map[int]bool{12: true, 15: true}, owner: "o", repo: "r"}
+	gh.ownerOnce.Do(func() {})
+	// nothing is fetched, or the nil client would panic
+	if prs := gh.pullRequests([]int{12, 15}, true); len(prs) != 0 {
+		t.Errorf("excluded pull requests should be left out: %v", prs)
+	}
+}
+
+func TestConfirmPRCount(t *testing.T) {
+	testCases := []struct {
+		name   string
+		maxPRs int
+		n      int
+		prompt io.Reader
+		err    bool
+	}{
+		{"within the limit", 10, 10, nil, false},
+		{"no terminal", 10, 11, nil, true},
+		{"confirmed", 10, 11, strings.NewReader("y\n"), false},
+		{"declined", 10, 11, strings.NewReader("n\n"), true},
+		{"no limit", 0, 5000, nil, false},
+	}
+	for _, tc := range testCases {
+		gh := &ghch{maxPRs: tc.maxPRs, prompt: tc.prompt}
+		err := gh.confirmPRCount("v0.1.0..v0.2.0", tc.n)
+		if tc.err != (err != nil) {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+
+	// asked only once per run
+	gh := &ghch{maxPRs: 10, prompt: strings.NewReader("y\n")}
+	for i := 0; i < 2; i++ {
+		if err := gh.confirmPRCount("v0.1.0..v0.2.0", 11); err != nil {
+			t.Errorf("unexpected error after confirmation: %s", err)
+		}
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for in, want := range map[string]bool{"y\n": true, "Yes\n": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		if got := confirm(strings.NewReader(in), &out, "fetch?"); got != want {
+			t.Errorf("confirm(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestMarkIncomplete(t *testing.T) {
+	gh := &ghch{}
+	gh.unresolve(errors.New("failed to fetch #1"))
+	n := gh.unresolvedCount()
+
+	var s Section
+	gh.markIncomplete(&s, n)
+	if s.Incomplete || s.Errors != nil {
+		t.Errorf("errors before the section must not mark it: %+v", s)
+	}
+	gh.unresolve(errors.New("failed to fetch #2"))
+	gh.markIncomplete(&s, n)
+	if !s.Incomplete || !reflect.DeepEqual(s.Errors, []string{"failed to fetch #2"}) {
+		t.Errorf("got %+v", s)
+	}
+	if code := gh.exitCode(false, false); code != exitCodePartial {
+		t.Errorf("got exit code %d, expect %d", code, exitCodePartial)
+	}
+}
+
+func TestEditNeedsWrite(t *testing.T) {
+	if _, err := (&ghOpts{Edit: true}).newGhch(); err == nil {
+		t.Error("--edit without --write should be an error")
+	}
+}
+
+func TestRelativeGitDir(t *testing.T) {
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	repo.Commit("initial commit")
+	repo.Tag("v0.1.0")
+	repo.Commit("fix")
+	repo.Tag("v0.1.0-rc.1")
+	repo.Tag("v0.2.0")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(cwd, filepath.Join(repo.Dir, ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig, had := os.LookupEnv("GIT_DIR")
+	os.Setenv("GIT_DIR", rel)
+	defer func() {
+		if had {
+			os.Setenv("GIT_DIR", orig)
+		} else {
+			os.Unsetenv("GIT_DIR")
+		}
+	}()
+
+	gh := (&ghch{gitPath: "git"}).initialize()
+	if got := os.Getenv("GIT_DIR"); got != rel {
+		t.Errorf("$GIT_DIR of the process should be left as it was: %s", got)
+	}
+	if expect := []string{"v0.2.0", "v0.1.0", "v0.1.0-rc.1"}; !reflect.DeepEqual(gh.versions(), expect) {
+		t.Errorf("got %v, expect %v", gh.versions(), expect)
+	}
+	// hooks running in the work tree get the absolute $GIT_DIR
+	out, err := (&hook{Exec: "git rev-parse --absolute-git-dir"}).run(gh.workDir(), gh.gitEnv(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != gh.gitDir {
+		t.Errorf("got %s, expect %s", got, gh.gitDir)
+	}
 }
@@ -0,0 +1,134 @@
+package ghchtest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Repo builds a git repository of merged pull requests and tags. Commits
+// are a minute apart from 2024-06-01 so that outputs are reproducible.
+type Repo struct {
+	// given to ghch by --repo
+	Dir string
+
+	t      testing.TB
+	now    time.Time
+	files  int
+	remote string
+}
+
+// NewRepo initializes a repository on master whose origin is a bare
+// repository at .../owner/repo.git, so that ghch takes owner/repo from it
+// while fetching and pushing stay local. Both are removed at the end of the
+// test.
+func NewRepo(t testing.TB, owner, repo string) *Repo {
+	t.Helper()
+	root, err := ioutil.TempDir("", "ghchtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	r := &Repo{Dir: filepath.Join(root, "work"), t: t, now: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	r.remote = filepath.Join(root, owner, repo+".git")
+	for _, dir := range []string{r.Dir, r.remote} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Git("init", "-q", "--bare", r.remote)
+	r.Git("init", "-q")
+	r.Git("symbolic-ref", "HEAD", "refs/heads/master")
+	r.Git("remote", "add", "origin", r.remote)
+	r.Commit("initial commit")
+	return r
+}
+
+// Git runs git in the repository as the fixture author and returns its
+// output, failing the test on errors. User and system configs are ignored.
+func (r *Repo) Git(args ...string) string {
+	r.t.Helper()
+	date := r.now.Format(time.RFC3339)
+	cmd := exec.Command("git", append([]string{"-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(),
+		"HOME="+r.Dir,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=ghchtest", "GIT_AUTHOR_EMAIL=ghchtest@example.com", "GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_NAME=ghchtest", "GIT_COMMITTER_EMAIL=ghchtest@example.com", "GIT_COMMITTER_DATE="+date,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		r.t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Commit commits a new file with msg on the current branch and returns its SHA
+func (r *Repo) Commit(msg string) string {
+	r.t.Helper()
+	r.now = r.now.Add(time.Minute)
+	r.files++
+	name := fmt.Sprintf("file%d.txt", r.files)
+	if err := ioutil.WriteFile(filepath.Join(r.Dir, name), []byte(msg+"\n"), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+	r.Git("add", name)
+	r.Git("commit", "-q", "-m", msg)
+	return r.Git("rev-parse", "HEAD")
+}
+
+// MergePR merges a branch of user with a commit of title into master by a
+// merge commit like GitHub does, and returns the pull request to serve
+func (r *Repo) MergePR(num int, user, title string) PullRequest {
+	r.t.Helper()
+	branch := fmt.Sprintf("pr-%d", num)
+	r.Git("checkout", "-q", "-b", branch)
+	r.Commit(title)
+	r.Git("checkout", "-q", "master")
+	r.now = r.now.Add(time.Minute)
+	r.Git("merge", "-q", "--no-ff", "-m", fmt.Sprintf("Merge pull request #%d from %s/%s", num, user, branch), "-m", title, branch)
+	r.Git("branch", "-q", "-D", branch)
+	return PullRequest{Number: num, Title: title, User: user, MergedAt: r.now, MergeCommitSHA: r.Git("rev-parse", "HEAD")}
+}
+
+// SquashPR commits "title (#num)" onto master like a squash merge, and
+// returns the pull request to serve
+func (r *Repo) SquashPR(num int, user, title string) PullRequest {
+	r.t.Helper()
+	sha := r.Commit(fmt.Sprintf("%s (#%d)", title, num))
+	return PullRequest{Number: num, Title: title, User: user, MergedAt: r.now, MergeCommitSHA: sha}
+}
+
+// Tag tags HEAD with an annotated tag
+func (r *Repo) Tag(name string) {
+	r.t.Helper()
+	r.Git("tag", "-a", "-m", name, name)
+}
+
+// Push pushes master and the tags to origin
+func (r *Repo) Push() {
+	r.t.Helper()
+	r.Git("push", "-q", "--tags", "origin", "master")
+}
+
+// Clone clones origin into another repository, which stays behind as r
+// pushes more, like a stale checkout
+func (r *Repo) Clone() *Repo {
+	r.t.Helper()
+	dir, err := ioutil.TempDir(filepath.Dir(r.Dir), "clone")
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	c := &Repo{Dir: dir, t: r.t, now: r.now, files: r.files, remote: r.remote}
+	c.Git("clone", "-q", r.remote, dir)
+	return c
+}
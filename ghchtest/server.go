@@ -0,0 +1,314 @@
+// Package ghchtest provides a fake GitHub API and fixture git repositories
+// to test changelog pipelines built on ghch hermetically.
+package ghchtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PullRequest is a pull request served by the Server, not merged when
+// MergedAt is zero
+type PullRequest struct {
+	Number   int
+	Title    string
+	User     string
+	Body     string
+	Labels   []string
+	Files    []string
+	MergedAt time.Time
+	// the merge commit, as returned by Repo.MergePR or Repo.SquashPR
+	MergeCommitSHA string
+	Additions      int
+	Deletions      int
+	Comments       int
+	ThumbsUp       int
+}
+
+// Release is a GitHub release served by the Server
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name,omitempty"`
+	Body        string    `json:"body,omitempty"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	CreatedAt   time.Time `json:"created_at"`
+	PublishedAt time.Time `json:"published_at"`
+	// the commit of the tag, served by git/refs/tags/<tag>
+	Commit          string `json:"-"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+}
+
+// Server is a fake of the GitHub API endpoints ghch calls: repositories,
+// pull requests, their files, issues for labels and reactions, and releases
+// with their tags and generated notes. Unknown pull requests and endpoints are 404 Not Found.
+type Server struct {
+	*httptest.Server
+
+	// when not empty, requests without "token <Token>" are 401 Unauthorized
+	Token string
+	// X-RateLimit-Limit of responses, and the remaining count decreases
+	// with each request (default: 5000)
+	RateLimit int
+
+	mu       sync.Mutex
+	prs      map[string]map[int]*PullRequest
+	releases map[string][]*Release
+	parents  map[string][2]string
+	notes    map[string]string
+	requests []string
+}
+
+// NewServer starts a fake GitHub API, which should be closed by Close
+func NewServer() *Server {
+	s := &Server{
+		RateLimit: 5000,
+		prs:       make(map[string]map[int]*PullRequest),
+		releases:  make(map[string][]*Release),
+		parents:   make(map[string][2]string),
+		notes:     make(map[string]string),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// APIURL is given to ghch by --api-url
+func (s *Server) APIURL() string {
+	return s.URL + "/"
+}
+
+// AddPullRequest serves the pull request of owner/repo
+func (s *Server) AddPullRequest(owner, repo string, pr PullRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := owner + "/" + repo
+	if s.prs[key] == nil {
+		s.prs[key] = make(map[int]*PullRequest)
+	}
+	s.prs[key][pr.Number] = &pr
+}
+
+// AddRelease serves the release of owner/repo
+func (s *Server) AddRelease(owner, repo string, r Release) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := owner + "/" + repo
+	s.releases[key] = append(s.releases[key], &r)
+}
+
+// AddFork serves owner/repo as a fork of parentOwner/parentRepo
+func (s *Server) AddFork(owner, repo, parentOwner, parentRepo string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parents[owner+"/"+repo] = [2]string{parentOwner, parentRepo}
+}
+
+// SetNotes serves body as the release notes generated for owner/repo
+func (s *Server) SetNotes(owner, repo, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notes[owner+"/"+repo] = body
+}
+
+// Releases returns the releases of owner/repo including those created by
+// `ghch release`, newest first
+func (s *Server) Releases(owner, repo string) []Release {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rels := s.releases[owner+"/"+repo]
+	ret := make([]Release, 0, len(rels))
+	for i := len(rels) - 1; i >= 0; i-- {
+		ret = append(ret, *rels[i])
+	}
+	return ret
+}
+
+// Requests returns the requests served so far like "GET /repos/o/r/pulls/1"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+var (
+	repoReg     = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)$`)
+	pullReg     = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/pulls/([0-9]+)$`)
+	filesReg    = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/pulls/([0-9]+)/files$`)
+	issueReg    = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/issues/([0-9]+)$`)
+	releasesReg = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/releases$`)
+	notesReg    = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/releases/generate-notes$`)
+	tagRefReg   = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/git/refs/tags/(.+)$`)
+)
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	remaining := s.RateLimit - len(s.requests)
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.RateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+
+	if s.Token != "" && r.Header.Get("Authorization") != "token "+s.Token {
+		writeJSON(w, http.StatusUnauthorized, message("Bad credentials"))
+		return
+	}
+	path := r.URL.Path
+	if m := releasesReg.FindStringSubmatch(path); m != nil {
+		s.serveReleases(w, r, m[1]+"/"+m[2])
+		return
+	}
+	if m := notesReg.FindStringSubmatch(path); m != nil && r.Method == http.MethodPost {
+		writeJSON(w, http.StatusOK, map[string]string{"name": "", "body": s.notes[m[1]+"/"+m[2]]})
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusNotFound, message("Not Found"))
+		return
+	}
+	if m := repoReg.FindStringSubmatch(path); m != nil {
+		writeJSON(w, http.StatusOK, s.repoJSON(m[1], m[2]))
+		return
+	}
+	if m := tagRefReg.FindStringSubmatch(path); m != nil {
+		for _, rel := range s.releases[m[1]+"/"+m[2]] {
+			if rel.TagName == m[3] && rel.Commit != "" {
+				writeJSON(w, http.StatusOK, map[string]interface{}{
+					"ref":    "refs/tags/" + rel.TagName,
+					"object": map[string]string{"type": "commit", "sha": rel.Commit},
+				})
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, message("Not Found"))
+		return
+	}
+	var m []string
+	for _, reg := range []*regexp.Regexp{pullReg, filesReg, issueReg} {
+		if m = reg.FindStringSubmatch(path); m != nil {
+			break
+		}
+	}
+	if m == nil {
+		writeJSON(w, http.StatusNotFound, message("Not Found"))
+		return
+	}
+	num, _ := strconv.Atoi(m[3])
+	pr, ok := s.prs[m[1]+"/"+m[2]][num]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, message("Not Found"))
+		return
+	}
+	web := "https://github.com/" + m[1] + "/" + m[2]
+	switch {
+	case pullReg.MatchString(path):
+		writeJSON(w, http.StatusOK, pullJSON(pr, m[1], m[2], web))
+	case filesReg.MatchString(path):
+		files := make([]map[string]string, 0, len(pr.Files))
+		for _, f := range pr.Files {
+			files = append(files, map[string]string{"filename": f})
+		}
+		writeJSON(w, http.StatusOK, files)
+	default:
+		labels := make([]map[string]string, 0, len(pr.Labels))
+		for _, l := range pr.Labels {
+			labels = append(labels, map[string]string{"name": l})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"number":    pr.Number,
+			"title":     pr.Title,
+			"labels":    labels,
+			"comments":  pr.Comments,
+			"reactions": map[string]int{"+1": pr.ThumbsUp},
+		})
+	}
+}
+
+// repoJSON serves any repository, which is a fork when added by AddFork
+func (s *Server) repoJSON(owner, repo string) map[string]interface{} {
+	v := map[string]interface{}{
+		"name":  repo,
+		"owner": map[string]string{"login": owner},
+		"fork":  false,
+	}
+	if p, ok := s.parents[owner+"/"+repo]; ok {
+		v["fork"] = true
+		v["parent"] = map[string]interface{}{"name": p[1], "owner": map[string]string{"login": p[0]}}
+	}
+	return v
+}
+
+func (s *Server) serveReleases(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		rels := s.releases[key]
+		ret := make([]*Release, 0, len(rels))
+		for i := len(rels) - 1; i >= 0; i-- {
+			ret = append(ret, rels[i])
+		}
+		writeJSON(w, http.StatusOK, ret)
+	case http.MethodPost:
+		var rel Release
+		if err := json.NewDecoder(r.Body).Decode(&rel); err != nil || rel.TagName == "" {
+			writeJSON(w, http.StatusUnprocessableEntity, message("Validation Failed"))
+			return
+		}
+		rel.CreatedAt = time.Now().UTC()
+		if !rel.Draft {
+			rel.PublishedAt = rel.CreatedAt
+		}
+		s.releases[key] = append(s.releases[key], &rel)
+		writeJSON(w, http.StatusCreated, rel)
+	default:
+		writeJSON(w, http.StatusNotFound, message("Not Found"))
+	}
+}
+
+func pullJSON(pr *PullRequest, owner, repo, web string) map[string]interface{} {
+	user := map[string]string{"login": pr.User}
+	repoJSON := map[string]interface{}{
+		"owner":     map[string]string{"login": owner},
+		"name":      repo,
+		"full_name": owner + "/" + repo,
+		"html_url":  web,
+	}
+	v := map[string]interface{}{
+		"html_url":         fmt.Sprintf("%s/pull/%d", web, pr.Number),
+		"number":           pr.Number,
+		"state":            "closed",
+		"title":            pr.Title,
+		"body":             pr.Body,
+		"user":             user,
+		"merged":           !pr.MergedAt.IsZero(),
+		"merge_commit_sha": pr.MergeCommitSHA,
+		"comments":         pr.Comments,
+		"additions":        pr.Additions,
+		"deletions":        pr.Deletions,
+		"changed_files":    len(pr.Files),
+		"head":             map[string]interface{}{"ref": "branch", "user": user, "repo": repoJSON},
+		"base":             map[string]interface{}{"ref": "master", "user": map[string]string{"login": owner}, "repo": repoJSON},
+	}
+	if !pr.MergedAt.IsZero() {
+		v["merged_at"] = pr.MergedAt.UTC().Format(time.RFC3339)
+	}
+	return v
+}
+
+func message(msg string) map[string]string {
+	return map[string]string{"message": msg}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,67 @@
+package ghchtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.Token = "secret"
+	srv.AddPullRequest("o", "r", PullRequest{Number: 1, Title: "Add a feature", User: "alice", Labels: []string{"feature"}})
+
+	get := func(path, token string) *http.Response {
+		req, _ := http.NewRequest("GET", srv.APIURL()+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "token "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	testCases := []struct {
+		path, token string
+		status      int
+	}{
+		{"repos/o/r/pulls/1", "secret", http.StatusOK},
+		{"repos/o/r/pulls/1", "", http.StatusUnauthorized},
+		{"repos/o/r/pulls/2", "secret", http.StatusNotFound},
+		{"repos/o/other/pulls/1", "secret", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		resp := get(tc.path, tc.token)
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: got %d, expect %d", tc.path, resp.StatusCode, tc.status)
+		}
+	}
+
+	resp := get("repos/o/r/issues/1", "secret")
+	defer resp.Body.Close()
+	var issue struct {
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
+		t.Fatal(err)
+	}
+	if len(issue.Labels) != 1 || issue.Labels[0].Name != "feature" {
+		t.Errorf("unexpected labels: %+v", issue.Labels)
+	}
+	if got := resp.Header.Get("X-RateLimit-Remaining"); got != "4995" {
+		t.Errorf("got remaining %s, expect 4995", got)
+	}
+	expect := []string{
+		"GET /repos/o/r/pulls/1", "GET /repos/o/r/pulls/1", "GET /repos/o/r/pulls/2",
+		"GET /repos/o/other/pulls/1", "GET /repos/o/r/issues/1",
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+}
@@ -0,0 +1,69 @@
+package ghch
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// modes of --group-by
+const (
+	groupByYear  = "year"
+	groupByMonth = "month"
+)
+
+func validGroupBy(groupBy string) error {
+	switch groupBy {
+	case "", groupByYear, groupByMonth:
+		return nil
+	}
+	return errors.Errorf("unknown --group-by %q: must be year or month", groupBy)
+}
+
+// Group is passed to the "group" template heading sections of a period
+type Group struct {
+	Name string
+	Date time.Time
+}
+
+func newGroup(groupBy string, t time.Time) *Group {
+	switch groupBy {
+	case groupByYear:
+		return &Group{Name: t.Format("2006"), Date: time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())}
+	case groupByMonth:
+		return &Group{Name: t.Format("January 2006"), Date: time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())}
+	}
+	return nil
+}
+
+// groupHeading renders the heading of g, if the section at t starts a group
+// other than prev
+func (r *renderer) groupHeading(prev *Group, t time.Time) (*Group, string, error) {
+	g := newGroup(r.groupBy, t)
+	if g == nil || (prev != nil && prev.Name == g.Name) {
+		return prev, "", nil
+	}
+	var b bytes.Buffer
+	if err := r.tmpl.ExecuteTemplate(&b, "group", g); err != nil {
+		return nil, "", err
+	}
+	return g, strings.TrimSpace(b.String()), nil
+}
+
+// demoteHeadings lowers markdown headings by a level to nest them in a group,
+// except in code blocks
+func demoteHeadings(str string) string {
+	lines := strings.Split(str, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+		}
+		if !inCode && strings.HasPrefix(line, "#") {
+			lines[i] = "#" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,38 @@
+package ghch
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupBy(t *testing.T) {
+	at := func(y int, m time.Month) time.Time {
+		return time.Date(y, m, 10, 0, 0, 0, 0, time.UTC)
+	}
+	chlog := Changelog{Sections: []Section{
+		{ToRevision: "v0.3.0", ChangedAt: at(2017, 1), Owner: "Songmu", Repo: "ghch"},
+		{ToRevision: "v0.2.0", ChangedAt: at(2016, 12), Owner: "Songmu", Repo: "ghch"},
+		{ToRevision: "v0.1.0", ChangedAt: at(2016, 3), Owner: "Songmu", Repo: "ghch"},
+	}}
+	r := &renderer{tmpl: mdTmpl, groupBy: groupByYear}
+	got, err := r.changelog(chlog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var headings []string
+	for _, line := range strings.Split(got, "\n") {
+		if strings.HasPrefix(line, "#") {
+			headings = append(headings, strings.SplitN(line, "]", 2)[0])
+		}
+	}
+	expect := []string{"## 2017", "### [v0.3.0", "## 2016", "### [v0.2.0", "### [v0.1.0"}
+	if strings.Join(headings, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("got %q, expect %q", headings, expect)
+	}
+
+	r.groupBy = groupByMonth
+	if got, _ := r.changelog(chlog); !strings.Contains(got, "## December 2016\n\n### [v0.2.0]") {
+		t.Errorf("sections should be grouped by month:\n%s", got)
+	}
+}
@@ -0,0 +1,134 @@
+package ghch
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// hook transforms a section between data collection and rendering. It is
+// either an external command, which reads the section as JSON from stdin
+// and writes the transformed one to stdout, or a Go plugin exporting
+// `func Transform([]byte) ([]byte, error)` working on the same JSON.
+type hook struct {
+	Exec   string `yaml:"exec"`
+	Plugin string `yaml:"plugin"`
+
+	once      sync.Once
+	transform func([]byte) ([]byte, error)
+	err       error
+}
+
+func (h *hook) String() string {
+	if h.Plugin != "" {
+		return h.Plugin
+	}
+	return h.Exec
+}
+
+func (h *hook) validate() error {
+	if (strings.TrimSpace(h.Exec) == "") == (h.Plugin == "") {
+		return errors.New("a hook needs either exec or plugin")
+	}
+	return nil
+}
+
+// run runs the hook in dir. env is the environment of an external command,
+// or that of ghch when nil.
+func (h *hook) run(dir string, env []string, in []byte) ([]byte, error) {
+	if h.Plugin != "" {
+		h.once.Do(func() {
+			p := h.Plugin
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(dir, p)
+			}
+			h.transform, h.err = openPluginTransform(p)
+		})
+		if h.err != nil {
+			return nil, h.err
+		}
+		return h.transform(in)
+	}
+	argv := splitCommand(h.Exec)
+	if len(argv) == 0 {
+		return nil, errors.New("no command to exec")
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(in)
+	var b bytes.Buffer
+	cmd.Stdout = &b
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// runHooks passes the section through the hooks of the config in order. A
+// failing hook leaves the section as it was.
+func (gh *ghch) runHooks(s Section) Section {
+	if len(gh.config.Hooks) == 0 {
+		return s
+	}
+	dir, env := gh.workDir(), gh.gitEnv()
+	for _, h := range gh.config.Hooks {
+		ns, err := applyHook(h, dir, env, s)
+		if err != nil {
+			gh.fail(errors.Wrapf(err, "hook %s failed", h))
+			continue
+		}
+		s = ns
+	}
+	return s
+}
+
+func applyHook(h *hook, dir string, env []string, s Section) (Section, error) {
+	in, err := json.Marshal(s)
+	if err != nil {
+		return s, err
+	}
+	out, err := h.run(dir, env, in)
+	if err != nil {
+		return s, err
+	}
+	var ns Section
+	if err := json.Unmarshal(out, &ns); err != nil {
+		return s, errors.Wrap(err, "failed to decode its output")
+	}
+	// restore what is not in JSON
+	ns.WebURL = s.WebURL
+	ns.Vars = s.Vars
+	files := make(map[int][]string)
+	for _, pr := range s.PullRequests {
+		files[pr.Number] = pr.Files
+	}
+	for _, pr := range ns.PullRequests {
+		pr.Files = files[pr.Number]
+	}
+	emails := make(map[string]string)
+	for _, c := range s.DirectCommits {
+		emails[c.SHA] = c.Email
+	}
+	for _, c := range ns.DirectCommits {
+		c.Email = emails[c.SHA]
+	}
+	return ns, nil
+}
+
+// hasCategory reports whether hooks have categorized any pull request
+func hasCategory(prs []*PullRequest) bool {
+	for _, pr := range prs {
+		if pr.Category != "" {
+			return true
+		}
+	}
+	return false
+}
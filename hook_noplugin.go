@@ -0,0 +1,10 @@
+//go:build !cgo || (!linux && !darwin)
+// +build !cgo !linux,!darwin
+
+package ghch
+
+import "github.com/pkg/errors"
+
+func openPluginTransform(path string) (func([]byte) ([]byte, error), error) {
+	return nil, errors.New("plugin hooks are not supported on this platform. use an exec hook instead")
+}
@@ -0,0 +1,29 @@
+//go:build cgo && (linux || darwin)
+// +build cgo
+// +build linux darwin
+
+package ghch
+
+import (
+	"plugin"
+
+	"github.com/pkg/errors"
+)
+
+func openPluginTransform(path string) (func([]byte) ([]byte, error), error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open plugin")
+	}
+	sym, err := p.Lookup("Transform")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to look up Transform")
+	}
+	switch fn := sym.(type) {
+	case func([]byte) ([]byte, error):
+		return fn, nil
+	case *func([]byte) ([]byte, error):
+		return *fn, nil
+	}
+	return nil, errors.Errorf("Transform of %s must be func([]byte) ([]byte, error)", path)
+}
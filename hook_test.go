@@ -0,0 +1,44 @@
+package ghch
+
+import (
+	"testing"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestApplyHook(t *testing.T) {
+	pr := &PullRequest{
+		PullRequest: &octokit.PullRequest{Number: 1, Title: "Fix leak of internal.example.com"},
+		Files:       []string{"main.go"},
+	}
+	s := Section{
+		PullRequests:  []*PullRequest{pr},
+		DirectCommits: []*Commit{{SHA: "abc1234", Subject: "Bump", Email: "songmu@example.com"}},
+		WebURL:        "https://git.example.com",
+		Vars:          map[string]string{"codename": "kiwi"},
+	}
+	h := &hook{Exec: `sed "s/internal\.example\.com/[redacted]/"`}
+	got, err := applyHook(h, ".", nil, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title := got.PullRequests[0].Title; title != "Fix leak of [redacted]" {
+		t.Errorf("title should be transformed: %q", title)
+	}
+	if got.WebURL != s.WebURL || got.Vars["codename"] != "kiwi" || len(got.PullRequests[0].Files) != 1 ||
+		got.DirectCommits[0].Email != "songmu@example.com" {
+		t.Errorf("fields out of JSON should be kept: %+v", got)
+	}
+
+	if _, err := applyHook(&hook{Exec: "false"}, ".", nil, s); err == nil {
+		t.Error("failing command should be an error")
+	}
+	for _, h := range []*hook{{}, {Exec: "  "}} {
+		if err := h.validate(); err == nil {
+			t.Errorf("hook without exec or plugin should be invalid: %+v", h)
+		}
+	}
+	if _, err := applyHook(&hook{Exec: " "}, ".", nil, s); err == nil {
+		t.Error("empty command should be an error")
+	}
+}
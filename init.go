@@ -0,0 +1,168 @@
+package ghch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type initOpts struct {
+	ghOpts
+	Templates string `long:"templates" description:"also write the default templates into the directory to customize them"`
+	Force     bool   `long:"force" description:"overwrite existing files"`
+}
+
+func (cli *CLI) runInit(argv []string) int {
+	opts := &initOpts{}
+	p, _, err := parseCommandArgs("init", opts, argv)
+	if err != nil {
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+
+	gh := (&ghch{
+		remote:   opts.Remote,
+		repoPath: opts.RepoPath,
+		gitDir:   opts.GitDir,
+		workTree: opts.WorkTree,
+		gitPath:  opts.GitPath,
+	}).initialize()
+
+	host := gh.remoteHost()
+	conf, err := starterConfig(host)
+	if err != nil {
+		log.Print(err)
+	}
+	files := map[string]string{gh.repoFile(defaultConfigFile): conf}
+	if opts.Templates != "" {
+		dir := gh.repoFile(opts.Templates)
+		for _, name := range exampleTemplates {
+			files[filepath.Join(dir, name+".tmpl")] = templateSource(name)
+		}
+	}
+	for _, file := range sortedKeys(files) {
+		if _, err := os.Stat(file); err == nil && !opts.Force {
+			log.Printf("%s already exists. pass --force to overwrite it", file)
+			return exitCodeErr
+		}
+	}
+	for _, file := range sortedKeys(files) {
+		if opts.DryRun {
+			fmt.Fprintf(cli.OutStream, "would write %s\n\n%s\n", file, files[file])
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			log.Print(errors.Wrapf(err, "failed to write %s", file))
+			return exitCodeErr
+		}
+		if err := ioutil.WriteFile(file, []byte(files[file]), 0644); err != nil {
+			log.Print(errors.Wrapf(err, "failed to write %s", file))
+			return exitCodeErr
+		}
+		fmt.Fprintln(cli.OutStream, file)
+	}
+	return exitCodeOK
+}
+
+// exampleTemplates are the templates written by `init --templates`
+var exampleTemplates = []string{"section", "item", "commit", "group"}
+
+var defineReg = regexp.MustCompile(`\{\{-? define "(\w+)"( -)?\}\}`)
+
+// templateSource extracts the source of a default template from tmplStr, to
+// keep its formatting in the files written by init
+func templateSource(name string) string {
+	locs := defineReg.FindAllStringSubmatchIndex(tmplStr, -1)
+	for i, loc := range locs {
+		if tmplStr[loc[2]:loc[3]] != name {
+			continue
+		}
+		end := len(tmplStr)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		src := strings.TrimRight(tmplStr[loc[1]:end], " \n")
+		src = strings.TrimSuffix(strings.TrimSuffix(src, "{{end}}"), "{{- end}}")
+		src = strings.TrimRight(src, " \n")
+		if loc[4] >= 0 {
+			src = strings.TrimLeft(src, " \n")
+		}
+		return src
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// remoteHost returns the host name of the URL of the remote
+func (gh *ghch) remoteHost() string {
+	out, err := gh.cmd("config", "--get", "remote."+gh.getRemote()+".url")
+	if err != nil {
+		return ""
+	}
+	return hostOfURL(strings.TrimSpace(out))
+}
+
+var scpLikeURLReg = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):`)
+
+// hostOfURL supports URLs like https://github.com/o/r.git,
+// ssh://git@github.com/o/r.git and git@github.com:o/r.git
+func hostOfURL(u string) string {
+	if strings.Contains(u, "://") {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return ""
+		}
+		return parsed.Hostname()
+	}
+	if matches := scpLikeURLReg.FindStringSubmatch(u); len(matches) > 1 {
+		return matches[1]
+	}
+	return ""
+}
+
+const starterRules = `rules:
+  - category: Breaking Changes
+    labels: [breaking, breaking-change]
+  - category: Features
+    labels: [enhancement, feature]
+  - category: Fixes
+    labels: [bug, bugfix, fix]
+  - category: Documentation
+    labels: [documentation, docs]
+  - category: Dependencies
+    labels: [dependencies]
+default_category: Other Changes
+`
+
+// starterConfig returns a config with common label mappings for the forge
+// hosting the remote. It is an error when the forge is not GitHub at all,
+// though the config is still returned.
+func starterConfig(host string) (string, error) {
+	head := "# generated by `ghch init`. see https://github.com/Songmu/ghch#configuration\n"
+	var err error
+	switch {
+	case host == "" || host == "github.com":
+	case strings.Contains(host, "gitlab") || strings.Contains(host, "bitbucket"):
+		err = errors.Errorf("the remote is hosted on %s, while ghch fetches pull requests from GitHub", host)
+	default:
+		head += fmt.Sprintf("#\n# %s looks like GitHub Enterprise Server. run ghch with\n"+
+			"#   --api-url=https://%s/api/v3/ --web-url=https://%s\n", host, host, host)
+	}
+	return head + "\n" + starterRules, err
+}
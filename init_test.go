@@ -0,0 +1,54 @@
+package ghch
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestHostOfURL(t *testing.T) {
+	testCases := map[string]string{
+		"https://github.com/Songmu/ghch.git":           "github.com",
+		"ssh://git@ghe.example.com:2222/Songmu/ghch":   "ghe.example.com",
+		"git@github.com:Songmu/ghch.git":               "github.com",
+		"gitlab.example.com:group/project.git":         "gitlab.example.com",
+		"/var/cache/mirrors/mackerel-agent.git":        "",
+		"https://x-access-token@github.com/o/r.git":    "github.com",
+		"https://bitbucket.org/team/project.git":       "bitbucket.org",
+		"file:///var/cache/mirrors/mackerel-agent.git": "",
+	}
+	for u, expect := range testCases {
+		if got := hostOfURL(u); got != expect {
+			t.Errorf("hostOfURL(%q): got %q, expect %q", u, got, expect)
+		}
+	}
+}
+
+func TestStarterConfig(t *testing.T) {
+	for _, host := range []string{"github.com", "ghe.example.com", "gitlab.com"} {
+		conf, err := starterConfig(host)
+		if _, perr := parseConfig([]byte(conf), defaultConfigFile); perr != nil {
+			t.Errorf("%s: starter config should be valid: %s", host, perr)
+		}
+		if (err != nil) != (host == "gitlab.com") {
+			t.Errorf("%s: unexpected error: %v", host, err)
+		}
+		if ghe := strings.Contains(conf, "--api-url=https://ghe.example.com/api/v3/"); ghe != (host == "ghe.example.com") {
+			t.Errorf("%s: hint of GitHub Enterprise Server: %v", host, ghe)
+		}
+	}
+}
+
+func TestTemplateSource(t *testing.T) {
+	for _, name := range exampleTemplates {
+		src := templateSource(name)
+		tmpl, err := template.New(name).Funcs(tmplFuncs).Parse(src)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if got, expect := tmpl.Tree.Root.String(), mdTmpl.Lookup(name).Tree.Root.String(); got != expect {
+			t.Errorf("%s: got %q, expect %q", name, got, expect)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package ghch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// confirmPRCount guards against ranges resolving to more pull requests than
+// --max-prs, which are mostly mistakes like a wrong --from and would hammer
+// the API for hours. It asks for confirmation on a terminal, and refuses
+// otherwise.
+func (gh *ghch) confirmPRCount(desc string, n int) error {
+	if gh.maxPRs <= 0 || n <= gh.maxPRs || gh.confirmedMax {
+		return nil
+	}
+	msg := fmt.Sprintf("%s resolves to %d pull requests, more than --max-prs=%d", desc, n, gh.maxPRs)
+	if gh.prompt != nil && confirm(gh.prompt, os.Stderr, msg+". fetch all of them?") {
+		gh.confirmedMax = true
+		return nil
+	}
+	return errors.Errorf("%s. check the range, or pass --max-prs=0 for an intentional full-history run", msg)
+}
+
+// terminalInput returns stdin to answer confirmations if it is a terminal
+func terminalInput() io.Reader {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return os.Stdin
+}
+
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
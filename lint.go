@@ -0,0 +1,415 @@
+package ghch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type lintOpts struct {
+	Format string `short:"F" long:"format" default:"text" description:"text or json"`
+	Scheme string `long:"version-scheme" default:"semver" description:"versions of headings: \"semver\", \"calver\" or \"regex:<pattern>\" as in generating"`
+	Quiet  bool   `short:"q" long:"quiet" description:"suppress all output and just exit with the result"`
+}
+
+func (cli *CLI) runLint(argv []string) int {
+	opts := &lintOpts{}
+	p, args, err := parseCommandArgs("lint", opts, argv)
+	if err != nil {
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+	if opts.Format != "text" && opts.Format != "json" {
+		log.Printf("unsupported format %q for lint: must be text or json", opts.Format)
+		return exitCodeParseFlagError
+	}
+	scheme, err := parseVersionScheme(opts.Scheme)
+	if err != nil {
+		log.Print(err)
+		return exitCodeParseFlagError
+	}
+
+	file := "CHANGELOG.md"
+	if len(args) > 0 {
+		file = args[0]
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Print(errors.Wrap(err, "failed to read changelog"))
+		return exitCodeErr
+	}
+	findings := lintChangelog(string(b), scheme)
+	for i := range findings {
+		findings[i].File = file
+	}
+	if opts.Format == "json" {
+		if findings == nil {
+			findings = []lintFinding{}
+		}
+		jsn, _ := json.MarshalIndent(findings, "", "  ")
+		fmt.Fprintln(cli.OutStream, string(jsn))
+	} else {
+		for _, f := range findings {
+			fmt.Fprintln(cli.OutStream, f)
+		}
+	}
+	if len(findings) > 0 {
+		return exitCodeFindings
+	}
+	return exitCodeOK
+}
+
+// lintFinding is a violation of the changelog conventions of ghch
+type lintFinding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (f lintFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", f.File, f.Line, f.Message, f.Rule)
+}
+
+var (
+	headingReg     = regexp.MustCompile(`^(#+)\s+(.*)$`)
+	sectionHeadReg = regexp.MustCompile(`^(?:\[([^\]]+)\](?:\(([^)]*)\))?|(\S+))(?:\s+\(([^)]*)\)|\s+-\s+(\S+))?$`)
+	mdLinkReg      = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]*)\)`)
+	pullLinkReg    = regexp.MustCompile(`/pull/([^/?#]+)$`)
+	releaseLinkReg = regexp.MustCompile(`/releases/tag/([^/?#]+)$`)
+	compareLinkReg = regexp.MustCompile(`/compare/([^/?#]+)\.\.\.([^/?#]+)$`)
+	groupHeadReg   = regexp.MustCompile(`^(?:[0-9]{4}|[0-9]{4}-[0-9]{2}|[A-Z][a-z]+ [0-9]{4})$`)
+)
+
+// sectionHeading is a version heading either of ghch, "[v1.2.3](url)
+// (2006-01-02)", or of Keep a Changelog, "[1.2.3] - 2006-01-02"
+type sectionHeading struct {
+	version string
+	href    string
+	date    string
+}
+
+func parseSectionHeading(text string) (h sectionHeading, ok bool) {
+	m := sectionHeadReg.FindStringSubmatch(text)
+	if m == nil {
+		return h, false
+	}
+	h.version, h.href, h.date = m[1], m[2], m[4]
+	if h.version == "" {
+		h.version = m[3]
+	}
+	if h.date == "" {
+		h.date = m[5]
+	}
+	return h, true
+}
+
+// lintSection is a version heading found by the linter
+type lintSection struct {
+	line    int
+	version string
+	date    time.Time
+}
+
+// lintChangelog checks heading structure, ordering and uniqueness of
+// versions, dates, and pull request, release and compare links. Versions are
+// semver unless the scheme is given.
+func lintChangelog(str string, scheme versionScheme) (findings []lintFinding) {
+	add := func(line int, rule, format string, args ...interface{}) {
+		findings = append(findings, lintFinding{Line: line, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	lines := splitLines(str)
+	var (
+		sections []lintSection
+		seen     = make(map[string]int)
+		level    = 0
+		inCode   = false
+		titled   = false
+		// versions are nested in year or month headings by --group-by
+		grouped  = isGroupedChangelog(lines)
+		verDepth = 2
+	)
+	if grouped {
+		verDepth = 3
+	}
+	verPrefix := strings.Repeat("#", verDepth) + " "
+	for i, line := range lines {
+		n := i + 1
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+		}
+		if inCode {
+			continue
+		}
+		if !strings.HasPrefix(line, verPrefix) {
+			// links in version headings are checked along with them
+			for _, m := range mdLinkReg.FindAllStringSubmatch(line, -1) {
+				lintLink(n, m[1], m[2], "", add)
+			}
+		}
+		m := headingReg.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		depth, text := len(m[1]), strings.TrimSpace(m[2])
+		switch {
+		case depth == 1:
+			if titled || len(sections) > 0 {
+				add(n, "heading", "title %q should be the only top-level heading at the top", text)
+			}
+			titled = true
+		case depth > level+1 && level > 0:
+			add(n, "heading", "heading level jumps from %d to %d", level, depth)
+		case grouped && depth == 2:
+			if !groupHeadReg.MatchString(text) {
+				add(n, "heading", "group heading %q should be a year or a month", text)
+			}
+		case depth == verDepth+1 && len(sections) == 0:
+			add(n, "heading", "heading %q is outside of any version section", text)
+		}
+		level = depth
+		if depth != verDepth {
+			continue
+		}
+
+		hm, ok := parseSectionHeading(text)
+		if !ok {
+			add(n, "heading", "version heading %q should be like \"[v1.2.3](url) (2006-01-02)\"", text)
+			continue
+		}
+		s := lintSection{line: n, version: hm.version}
+		unreleased := strings.EqualFold(s.version, "unreleased")
+		if !isLintVersion(scheme, s.version) && !unreleased {
+			add(n, "version", "%q is not a version", s.version)
+		}
+		if prev, ok := seen[s.version]; ok {
+			add(n, "duplicate", "%s is already listed at line %d", s.version, prev)
+		}
+		seen[s.version] = n
+		if hm.date == "" {
+			// the unreleased section of Keep a Changelog has no date
+			if !unreleased {
+				add(n, "date", "%s lacks its date", s.version)
+			}
+		} else if t, err := time.Parse("2006-01-02", hm.date); err != nil {
+			add(n, "date", "date %q of %s should be formatted as YYYY-MM-DD", hm.date, s.version)
+		} else {
+			s.date = t
+		}
+		if len(sections) > 0 {
+			prev := sections[len(sections)-1]
+			if compareSchemeVersions(scheme, prev.version, s.version) < 0 {
+				add(n, "order", "%s should be listed before %s at line %d", s.version, prev.version, prev.line)
+			}
+			if !prev.date.IsZero() && !s.date.IsZero() && s.date.After(prev.date) {
+				add(n, "date", "%s is dated after the newer %s at line %d", s.version, prev.version, prev.line)
+			}
+		}
+		if hm.href != "" {
+			lintLink(n, hm.version, hm.href, s.version, add)
+		}
+		sections = append(sections, s)
+	}
+	lintCompareLinks(str, sections, add)
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// isGroupedChangelog tells output of --group-by, whose first "## " heading is
+// a year or a month followed by "### " version headings
+func isGroupedChangelog(lines []string) bool {
+	inCode := false
+	group := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+		}
+		m := headingReg.FindStringSubmatch(line)
+		if inCode || m == nil || len(m[1]) == 1 {
+			continue
+		}
+		if !group {
+			if len(m[1]) != 2 || !groupHeadReg.MatchString(strings.TrimSpace(m[2])) {
+				return false
+			}
+			group = true
+			continue
+		}
+		return len(m[1]) == 3
+	}
+	return false
+}
+
+// lintLink checks a link against the conventions of ghch output. version is
+// given for the link of a version heading.
+func lintLink(line int, text, href, version string, add func(int, string, string, ...interface{})) {
+	u, err := url.Parse(href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add(line, "link", "broken link %q", href)
+		return
+	}
+	if m := pullLinkReg.FindStringSubmatch(u.Path); m != nil {
+		num, err := strconv.Atoi(m[1])
+		if err != nil {
+			add(line, "link", "pull request link %q has no number", href)
+		} else if strings.HasPrefix(text, "#") && text != "#"+m[1] {
+			add(line, "link", "%s links to pull request #%d", text, num)
+		}
+	}
+	if m := releaseLinkReg.FindStringSubmatch(u.Path); m != nil && version != "" && m[1] != version {
+		add(line, "link", "heading of %s links to release %s", version, m[1])
+	}
+}
+
+// lintCompareLinks checks that compare links of each section range from the
+// previous version to the version of the section
+func lintCompareLinks(str string, sections []lintSection, add func(int, string, string, ...interface{})) {
+	lines := splitLines(str)
+	for i, s := range sections {
+		end := len(lines)
+		if i+1 < len(sections) {
+			end = sections[i+1].line - 1
+		}
+		for n := s.line; n <= end; n++ {
+			for _, m := range mdLinkReg.FindAllStringSubmatch(lines[n-1], -1) {
+				cm := compareLinkReg.FindStringSubmatch(m[2])
+				if cm == nil {
+					continue
+				}
+				if cm[2] != s.version && !strings.EqualFold(s.version, "unreleased") {
+					add(n, "link", "compare link of %s ends at %s", s.version, cm[2])
+				}
+				if i+1 < len(sections) && cm[1] != sections[i+1].version {
+					add(n, "link", "compare link of %s starts at %s instead of the previous %s", s.version, cm[1], sections[i+1].version)
+				}
+			}
+		}
+	}
+}
+
+func isLintVersion(scheme versionScheme, ver string) bool {
+	if scheme != nil {
+		return scheme.match(ver)
+	}
+	_, ok := parseVersion(ver)
+	return ok
+}
+
+// compareSchemeVersions compares versions of the scheme like compareVersions
+func compareSchemeVersions(scheme versionScheme, a, b string) int {
+	if scheme == nil {
+		return compareVersions(a, b)
+	}
+	okA, okB := scheme.match(a), scheme.match(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return 1
+	case !okB:
+		return -1
+	case scheme.less(a, b):
+		return -1
+	case scheme.less(b, a):
+		return 1
+	}
+	return 0
+}
+
+var semverReg = regexp.MustCompile(`^v?([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+type semver struct {
+	nums [3]int
+	pre  string
+}
+
+func parseVersion(ver string) (semver, bool) {
+	m := semverReg.FindStringSubmatch(ver)
+	if m == nil {
+		return semver{}, false
+	}
+	var v semver
+	for i := 0; i < 3; i++ {
+		v.nums[i], _ = strconv.Atoi(m[i+1])
+	}
+	v.pre = m[4]
+	return v, true
+}
+
+// compareVersions compares versions by their precedence. Anything other than
+// a version, such as "Unreleased", is the newest.
+func compareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return 1
+	case !okB:
+		return -1
+	}
+	for i := 0; i < 3; i++ {
+		if va.nums[i] != vb.nums[i] {
+			if va.nums[i] < vb.nums[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case va.pre == vb.pre:
+		return 0
+	case va.pre == "":
+		return 1
+	case vb.pre == "":
+		return -1
+	}
+	return comparePrerelease(va.pre, vb.pre)
+}
+
+// comparePrerelease compares dot-separated identifiers of prereleases by the
+// precedence of semver: numerically when both are numbers, numbers before
+// others, and a prefix before longer ones, so rc.2 < rc.10 < rc.10.1
+func comparePrerelease(a, b string) int {
+	ia, ib := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(ia) && i < len(ib); i++ {
+		if ia[i] == ib[i] {
+			continue
+		}
+		na, errA := strconv.Atoi(ia[i])
+		nb, errB := strconv.Atoi(ib[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		case ia[i] < ib[i]:
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case len(ia) < len(ib):
+		return -1
+	case len(ia) > len(ib):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+package ghch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestLintChangelog(t *testing.T) {
+	input := "# Changelog\n" +
+		"\n" +
+		"## [v0.3.0](https://github.com/Songmu/ghch/releases/tag/v0.3.0) (2016-05-01)\n" +
+		"\n" +
+		"* Add lint [#12](https://github.com/Songmu/ghch/pull/13) ([Songmu](https://github.com/Songmu))\n" +
+		"* [Full changes](https://github.com/Songmu/ghch/compare/v0.0.9...v0.3.0)\n" +
+		"\n" +
+		"## [v0.1.0](https://github.com/Songmu/ghch/releases/tag/v0.1.0) (2016-05-02)\n" +
+		"\n" +
+		"#### Fixes\n" +
+		"\n" +
+		"## [v0.2.0](https://github.com/Songmu/ghch/releases/tag/v0.1.0) (2016/04/01)\n" +
+		"\n" +
+		"* broken [#10](github.com/Songmu/ghch/pull/10)\n" +
+		"\n" +
+		"## [v0.1.0](https://github.com/Songmu/ghch/releases/tag/v0.1.0) (2016-03-01)\n"
+	var got []string
+	for _, f := range lintChangelog(input, nil) {
+		got = append(got, f.Rule)
+		t.Logf("%d: %s (%s)", f.Line, f.Message, f.Rule)
+	}
+	expect := []string{
+		"link",      // #12 links to #13
+		"link",      // compare link starts at v0.0.9 instead of v0.1.0
+		"date",      // v0.1.0 dated after v0.3.0
+		"heading",   // #### right after ##
+		"date",      // 2016/04/01
+		"order",     // v0.2.0 after v0.1.0
+		"link",      // release link of v0.2.0
+		"link",      // relative link
+		"duplicate", // v0.1.0 twice
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %v, expect %v", got, expect)
+	}
+
+	clean := "# Changelog\n\n" +
+		"## [v0.2.0](https://github.com/Songmu/ghch/releases/tag/v0.2.0) (2016-05-01)\n\n" +
+		"### Features\n\n" +
+		"* Add lint [#12](https://github.com/Songmu/ghch/pull/12) ([Songmu](https://github.com/Songmu))\n\n" +
+		"## [v0.1.0](https://github.com/Songmu/ghch/releases/tag/v0.1.0) (2016-04-01)\n"
+	if findings := lintChangelog(clean, nil); len(findings) != 0 {
+		t.Errorf("clean changelog got findings: %+v", findings)
+	}
+	if findings := lintChangelog(strings.Replace(clean, "\n", "\r\n", -1), nil); len(findings) != 0 {
+		t.Errorf("clean changelog with CRLF got findings: %+v", findings)
+	}
+}
+
+func TestLintChangelogOfScheme(t *testing.T) {
+	input := "# Changelog\n\n" +
+		"## 2024.10 (2024-10-01)\n\n" +
+		"## 2024.06.1 (2024-06-15)\n\n" +
+		"## 2024.09 (2024-09-01)\n"
+	scheme, _ := parseVersionScheme("calver")
+	var got []string
+	for _, f := range lintChangelog(input, scheme) {
+		got = append(got, f.Rule)
+	}
+	if expect := []string{"order", "date"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	vers := []string{"Unreleased", "v1.0.0", "v1.0.0-rc.10", "v1.0.0-rc.2", "v1.0.0-rc.1", "v1.0.0-rc",
+		"v1.0.0-beta.11", "v1.0.0-beta.2", "v1.0.0-beta", "v1.0.0-alpha.beta", "v1.0.0-alpha.1", "v1.0.0-alpha",
+		"v0.10.0", "v0.9.1", "0.9"}
+	for i := 0; i+1 < len(vers); i++ {
+		if compareVersions(vers[i], vers[i+1]) <= 0 {
+			t.Errorf("%s should be newer than %s", vers[i], vers[i+1])
+		}
+	}
+	if compareVersions("v1.2.0", "1.2.0") != 0 {
+		t.Error("the v prefix should not matter")
+	}
+}
+
+func TestLintKeepAChangelog(t *testing.T) {
+	input := "# Changelog\n\n" +
+		"## [Unreleased]\n\n" +
+		"## [1.1.0] - 2024-02-01\n\n" +
+		"### Added\n\n" +
+		"- Add lint\n\n" +
+		"## 1.0.0 - 2024-01-01\n\n" +
+		"## [0.9.0] - 2024-03-01\n\n" +
+		"[Unreleased]: https://github.com/o/r/compare/v1.1.0...HEAD\n"
+	var got []string
+	for _, f := range lintChangelog(input, nil) {
+		got = append(got, f.Rule)
+	}
+	// 0.9.0 is dated after 1.0.0
+	if expect := []string{"date"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+}
+
+func TestLintGroupedChangelog(t *testing.T) {
+	at := func(y int, m time.Month) time.Time {
+		return time.Date(y, m, 10, 0, 0, 0, 0, time.UTC)
+	}
+	pr := &PullRequest{PullRequest: &octokit.PullRequest{
+		Number: 12, Title: "Add lint", User: octokit.User{Login: "Songmu"}, HTMLURL: "https://github.com/Songmu/ghch/pull/12",
+	}}
+	chlog := Changelog{Sections: []Section{
+		{ToRevision: "v0.3.0", FromRevision: "v0.2.0", ChangedAt: at(2017, 1), Owner: "Songmu", Repo: "ghch", PullRequests: []*PullRequest{pr}},
+		{ToRevision: "v0.2.0", FromRevision: "v0.1.0", ChangedAt: at(2016, 12), Owner: "Songmu", Repo: "ghch"},
+		{ToRevision: "v0.1.0", ChangedAt: at(2016, 3), Owner: "Songmu", Repo: "ghch"},
+	}}
+	for _, groupBy := range []string{groupByYear, groupByMonth} {
+		out, err := (&renderer{tmpl: mdTmpl, groupBy: groupBy}).changelog(chlog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if findings := lintChangelog("# Changelog\n\n"+out, nil); len(findings) != 0 {
+			t.Errorf("%s: output of --group-by got findings: %+v\n%s", groupBy, findings, out)
+		}
+	}
+
+	input := "# Changelog\n\n" +
+		"## 2017\n\n" +
+		"### [v0.3.0](https://github.com/Songmu/ghch/releases/tag/v0.3.0) (2017-01-10)\n\n" +
+		"## Misc\n\n" +
+		"### [v0.4.0](https://github.com/Songmu/ghch/releases/tag/v0.4.0) (2016-12-10)\n"
+	var got []string
+	for _, f := range lintChangelog(input, nil) {
+		got = append(got, f.Rule)
+	}
+	if expect := []string{"heading", "order"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+}
@@ -0,0 +1,114 @@
+package ghch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// milestone is a milestone listed by the API
+type milestone struct {
+	Number   int        `json:"number"`
+	Title    string     `json:"title"`
+	State    string     `json:"state"`
+	ClosedAt *time.Time `json:"closed_at"`
+}
+
+// milestoneIssue is an issue or a pull request attached to a milestone
+type milestoneIssue struct {
+	Number      int       `json:"number"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+// milestoneSection builds a section from the merged pull requests attached to
+// the milestone, regardless of tags. It is dated when the milestone was
+// closed, or now while it is open.
+func (gh *ghch) milestoneSection(title, nextVersion string) Section {
+	owner, repo := gh.ownerAndRepo()
+	m, err := gh.findMilestone(owner, repo, title)
+	if err != nil {
+		gh.fail(err)
+		return Section{ToRevision: title, Owner: owner, Repo: repo, WebURL: gh.getWebURL()}
+	}
+	nums, err := gh.milestonePRNums(owner, repo, m.Number)
+	if err != nil {
+		gh.fail(err)
+	}
+	if err := gh.confirmPRCount("milestone "+title, len(nums)); err != nil {
+		gh.fail(err)
+		nums = nil
+	}
+	unresolved := gh.unresolvedCount()
+	var prs []*PullRequest
+	for _, pr := range gh.pullRequests(nums, false) {
+		// closed without being merged
+		if pr.MergedAt != nil {
+			prs = append(prs, pr)
+		}
+	}
+	s := Section{
+		PullRequests: prs,
+		ToRevision:   title,
+		ChangedAt:    time.Now(),
+		Owner:        owner,
+		Repo:         repo,
+		WebURL:       gh.getWebURL(),
+	}
+	if nextVersion != "" {
+		s.ToRevision = nextVersion
+	}
+	if m.ClosedAt != nil {
+		s.ChangedAt = *m.ClosedAt
+	}
+	gh.markIncomplete(&s, unresolved)
+	return gh.finishSection(s)
+}
+
+func (gh *ghch) findMilestone(owner, repo, title string) (*milestone, error) {
+	path := fmt.Sprintf("repos/%s/%s/milestones?state=all&per_page=100", owner, repo)
+	for path != "" {
+		var page []*milestone
+		var err error
+		if path, err = gh.apiGet(path, &page); err != nil {
+			return nil, errors.Wrap(err, "failed to list milestones")
+		}
+		if m := pickMilestone(page, title); m != nil {
+			return m, nil
+		}
+	}
+	return nil, errors.Errorf("milestone %q is not found in %s/%s", title, owner, repo)
+}
+
+func pickMilestone(ms []*milestone, title string) *milestone {
+	for _, m := range ms {
+		if m.Title == title {
+			return m
+		}
+	}
+	return nil
+}
+
+// milestonePRNums lists the closed pull requests of the milestone oldest
+// first, as git log would
+func (gh *ghch) milestonePRNums(owner, repo string, number int) (nums []int, err error) {
+	path := fmt.Sprintf("repos/%s/%s/issues?milestone=%d&state=closed&sort=created&direction=asc&per_page=100",
+		owner, repo, number)
+	for path != "" {
+		var page []milestoneIssue
+		if path, err = gh.apiGet(path, &page); err != nil {
+			return nums, errors.Wrapf(err, "failed to list pull requests of milestone #%d", number)
+		}
+		nums = append(nums, milestoneIssueNums(page)...)
+	}
+	return nums, nil
+}
+
+func milestoneIssueNums(issues []milestoneIssue) (nums []int) {
+	for _, i := range issues {
+		if i.PullRequest != nil {
+			nums = append(nums, i.Number)
+		}
+	}
+	return nums
+}
@@ -0,0 +1,32 @@
+package ghch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMilestoneIssueNums(t *testing.T) {
+	var issues []milestoneIssue
+	err := json.Unmarshal([]byte(`[
+  {"number": 10, "pull_request": {"url": "https://api.github.com/repos/o/r/pulls/10"}},
+  {"number": 11},
+  {"number": 12, "pull_request": {}}
+]`), &issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expect := milestoneIssueNums(issues), []int{10, 12}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+}
+
+func TestPickMilestone(t *testing.T) {
+	ms := []*milestone{{Number: 1, Title: "v1.0"}, {Number: 3, Title: "v2.0"}}
+	if m := pickMilestone(ms, "v2.0"); m == nil || m.Number != 3 {
+		t.Errorf("v2.0 should be #3: %v", m)
+	}
+	if m := pickMilestone(ms, "v2"); m != nil {
+		t.Errorf("titles should match exactly: %v", m)
+	}
+}
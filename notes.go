@@ -0,0 +1,163 @@
+package ghch
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	githubNotesMerge = "merge"
+	githubNotesDiff  = "diff"
+)
+
+func validGitHubNotes(mode string) error {
+	switch mode {
+	case "", githubNotesMerge, githubNotesDiff:
+		return nil
+	}
+	return errors.Errorf("unknown --github-notes %q: must be merge or diff", mode)
+}
+
+// Contributor made the first contribution to the repository in the section,
+// as detected by the release notes GitHub generates
+type Contributor struct {
+	Login       string `json:"login"`
+	PullRequest int    `json:"pull_request"`
+}
+
+// githubNotes is the parsed output of the generate-notes API
+type githubNotes struct {
+	PullRequests    []int
+	NewContributors []*Contributor
+}
+
+// fetchGitHubNotes asks GitHub to generate the release notes of the section
+// between from and to. A section not tagged yet is generated on the head.
+func (gh *ghch) fetchGitHubNotes(from, to string) (*githubNotes, error) {
+	owner, repo := gh.ownerAndRepo()
+	params := map[string]string{"tag_name": to}
+	if t, ok := gh.tagRefs()[to]; ok {
+		// used only when the tag is not pushed yet
+		params["target_commitish"] = t.SHA
+	} else {
+		// GitHub takes a tag to be created as ending at the target
+		if to == "" {
+			params["tag_name"] = "Unreleased"
+		}
+		params["target_commitish"] = gh.compareRevision("")
+	}
+	if from != "" {
+		params["previous_tag_name"] = from
+	}
+	var res struct {
+		Body string `json:"body"`
+	}
+	path := fmt.Sprintf("repos/%s/%s/releases/generate-notes", owner, repo)
+	if _, err := gh.apiRequest("POST", path, params, &res); err != nil {
+		return nil, errors.Wrapf(err, "failed to generate release notes of %s on GitHub", params["tag_name"])
+	}
+	return parseGitHubNotes(res.Body), nil
+}
+
+var (
+	notesPullReg        = regexp.MustCompile(`/pull/([0-9]+)\b`)
+	notesContributorReg = regexp.MustCompile(`^\* @(\S+) made their first contribution in \S*/pull/([0-9]+)`)
+)
+
+// parseGitHubNotes reads pull requests of "What's Changed" and the "New
+// Contributors" out of the markdown generated by GitHub
+func parseGitHubNotes(body string) *githubNotes {
+	notes := &githubNotes{}
+	heading := ""
+	for _, line := range splitLines(body) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			heading = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+		if !strings.HasPrefix(line, "* ") {
+			continue
+		}
+		if heading == "New Contributors" {
+			if m := notesContributorReg.FindStringSubmatch(line); m != nil {
+				num, _ := strconv.Atoi(m[2])
+				notes.NewContributors = append(notes.NewContributors, &Contributor{Login: m[1], PullRequest: num})
+			}
+			continue
+		}
+		// entries end with "in https://github.com/o/r/pull/123"
+		if ms := notesPullReg.FindAllStringSubmatch(line, -1); len(ms) > 0 {
+			num, _ := strconv.Atoi(ms[len(ms)-1][1])
+			notes.PullRequests = appendUniqueNums(notes.PullRequests, num)
+		}
+	}
+	return notes
+}
+
+// mergeGitHubNotes adds the pull requests only GitHub found and the new
+// contributors to the section
+func (gh *ghch) mergeGitHubNotes(s *Section, notes *githubNotes, released bool) {
+	_, missing := diffPRNums(s.PullRequests, notes.PullRequests)
+	s.PullRequests = append(s.PullRequests, gh.pullRequests(missing, released)...)
+	s.NewContributors = notes.NewContributors
+}
+
+// diffPRNums returns the numbers only ghch has and those only GitHub has
+func diffPRNums(prs []*PullRequest, nums []int) (onlyGhch, onlyGitHub []int) {
+	have := make(map[int]bool)
+	for _, pr := range prs {
+		have[pr.Number] = true
+	}
+	listed := make(map[int]bool)
+	for _, n := range nums {
+		listed[n] = true
+		if !have[n] {
+			onlyGitHub = append(onlyGitHub, n)
+		}
+	}
+	for _, pr := range prs {
+		if !listed[pr.Number] {
+			onlyGhch = append(onlyGhch, pr.Number)
+		}
+	}
+	sort.Ints(onlyGhch)
+	sort.Ints(onlyGitHub)
+	return onlyGhch, onlyGitHub
+}
+
+// writeNotesDiff reports the differences between the section and the notes
+// GitHub generated, and tells whether there are any
+func writeNotesDiff(w io.Writer, s Section, notes *githubNotes) bool {
+	onlyGhch, onlyGitHub := diffPRNums(s.PullRequests, notes.PullRequests)
+	for _, n := range onlyGhch {
+		fmt.Fprintf(w, "-#%d only in ghch\n", n)
+	}
+	for _, n := range onlyGitHub {
+		fmt.Fprintf(w, "+#%d only in GitHub\n", n)
+	}
+	return len(onlyGhch)+len(onlyGitHub) > 0
+}
+
+// diffGitHubNotes prints the differences of the current section from the
+// notes GitHub generates, exiting with exitCodeDiffers when there are any
+func (cli *CLI) diffGitHubNotes(gh *ghch, opts *ghOpts) int {
+	s := gh.getCurrentSection(opts.From, opts.To, opts.NextVersion)
+	notes, err := gh.fetchGitHubNotes(s.FromRevision, s.ToRevision)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	differs := writeNotesDiff(cli.OutStream, s, notes)
+	// errors and missing pull requests make the differences unreliable
+	if code := gh.exitCode(false, false); code != exitCodeOK || !differs {
+		return code
+	}
+	return exitCodeDiffers
+}
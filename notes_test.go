@@ -0,0 +1,66 @@
+package ghch
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+const generatedNotes = "## What's Changed\r\n" +
+	"### Features\r\n" +
+	"* Add --limit by @alice in https://github.com/o/r/pull/12\r\n" +
+	"* Fix #3 on Windows by @bob in https://github.com/o/r/pull/15\r\n" +
+	"\r\n" +
+	"## New Contributors\r\n" +
+	"* @bob made their first contribution in https://github.com/o/r/pull/15\r\n" +
+	"\r\n" +
+	"**Full Changelog**: https://github.com/o/r/compare/v0.1.0...v0.2.0"
+
+func TestParseGitHubNotes(t *testing.T) {
+	notes := parseGitHubNotes(generatedNotes)
+	if expect := []int{12, 15}; !reflect.DeepEqual(notes.PullRequests, expect) {
+		t.Errorf("pull requests: got %v, expect %v", notes.PullRequests, expect)
+	}
+	expect := []*Contributor{{Login: "bob", PullRequest: 15}}
+	if !reflect.DeepEqual(notes.NewContributors, expect) {
+		t.Errorf("new contributors: got %v, expect %v", notes.NewContributors, expect)
+	}
+}
+
+func TestWriteNotesDiff(t *testing.T) {
+	s := Section{PullRequests: []*PullRequest{
+		{PullRequest: &octokit.PullRequest{Number: 12}},
+		{PullRequest: &octokit.PullRequest{Number: 13}},
+	}}
+	var b bytes.Buffer
+	if !writeNotesDiff(&b, s, parseGitHubNotes(generatedNotes)) {
+		t.Error("sections should differ")
+	}
+	if got, expect := b.String(), "-#13 only in ghch\n+#15 only in GitHub\n"; got != expect {
+		t.Errorf("got %q, expect %q", got, expect)
+	}
+	b.Reset()
+	if writeNotesDiff(&b, s, &githubNotes{PullRequests: []int{13, 12}}) || b.Len() > 0 {
+		t.Errorf("sections should be the same: %q", b.String())
+	}
+}
+
+func TestRenderNewContributors(t *testing.T) {
+	r := &renderer{tmpl: mdTmpl}
+	str, err := r.section(Section{
+		ToRevision:      "v0.2.0",
+		Owner:           "o",
+		Repo:            "r",
+		NewContributors: []*Contributor{{Login: "bob", PullRequest: 15}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "\n\n### New Contributors\n\n* [bob](https://github.com/bob) made their first contribution in [#15](https://github.com/o/r/pull/15)"
+	if !strings.HasSuffix(str, expect) {
+		t.Errorf("got %q", str)
+	}
+}
@@ -0,0 +1,266 @@
+package ghch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type notifyOpts struct {
+	ghOpts
+	Webhook string `long:"webhook" description:"URL to post the section to (default: $GHCH_WEBHOOK_URL)"`
+	Kind    string `long:"webhook-format" description:"\"slack\", \"teams\" or \"generic\" JSON, guessed from the URL by default"`
+	Payload string `long:"payload" description:"template file of the JSON payload, given the section with .Title and .Markdown"`
+	Retries int    `long:"retries" default:"3" description:"times to retry on network errors, rate limits and server errors"`
+}
+
+// kinds of webhooks
+const (
+	webhookSlack   = "slack"
+	webhookTeams   = "teams"
+	webhookGeneric = "generic"
+)
+
+// runNotify posts the section of --next-version or of the latest version to
+// a chat webhook, to announce the release right after generating its notes
+func (cli *CLI) runNotify(argv []string) int {
+	opts := &notifyOpts{}
+	p, _, err := parseCommandArgs("notify", opts, argv)
+	if err != nil {
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+	if opts.Webhook == "" {
+		opts.Webhook = os.Getenv("GHCH_WEBHOOK_URL")
+	}
+	if opts.Webhook == "" {
+		log.Print("no webhook to notify. specify --webhook or $GHCH_WEBHOOK_URL")
+		return exitCodeParseFlagError
+	}
+	kind := opts.Kind
+	if kind == "" {
+		kind = webhookKindOf(opts.Webhook)
+	}
+	switch kind {
+	case webhookSlack, webhookTeams, webhookGeneric:
+	default:
+		log.Printf("unknown --webhook-format %q: must be slack, teams or generic", kind)
+		return exitCodeParseFlagError
+	}
+
+	gh, err := opts.newGhch()
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	rdr, err := opts.newRenderer(gh.config)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	from, to, tag, err := gh.releaseRange(&opts.ghOpts)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	s := gh.getCurrentSection(from, to, tag)
+	md, err := rdr.section(s)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	data := notifyData{Section: s, Title: fmt.Sprintf("%s/%s %s", s.Owner, s.Repo, tag), Markdown: md}
+	if data.WebURL == "" {
+		data.WebURL = defaultWebURL
+	}
+
+	var payload []byte
+	if opts.Payload != "" {
+		payload, err = templatePayload(gh.repoFile(opts.Payload), data)
+	} else {
+		payload, err = webhookPayload(kind, data)
+	}
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	if opts.DryRun {
+		fmt.Fprintln(cli.OutStream, string(payload))
+		return gh.exitCode(false, false)
+	}
+	if err := postWebhook(opts.Webhook, payload, opts.Retries); err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	return gh.exitCode(false, false)
+}
+
+// notifyData is given to payload templates
+type notifyData struct {
+	Section
+	// like "Songmu/ghch v1.2.0"
+	Title string
+	// the section rendered by the templates
+	Markdown string
+}
+
+func webhookKindOf(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return webhookGeneric
+	}
+	host := strings.ToLower(parsed.Hostname())
+	switch {
+	case host == "hooks.slack.com":
+		return webhookSlack
+	case strings.HasSuffix(host, ".webhook.office.com") || host == "outlook.office.com":
+		return webhookTeams
+	}
+	return webhookGeneric
+}
+
+func webhookPayload(kind string, data notifyData) ([]byte, error) {
+	var v interface{}
+	switch kind {
+	case webhookSlack:
+		v = map[string]string{"text": slackText(data.Markdown)}
+	case webhookTeams:
+		// Office 365 connector card, whose text is markdown
+		v = map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  data.Title,
+			"title":    data.Title,
+			"text":     data.Markdown,
+		}
+	default:
+		v = struct {
+			Title    string  `json:"title"`
+			Markdown string  `json:"markdown"`
+			Section  Section `json:"section"`
+		}{data.Title, data.Markdown, data.Section}
+	}
+	b, err := marshalPayload(v)
+	return b, errors.Wrap(err, "failed to encode payload")
+}
+
+// marshalPayload keeps <, > and & of mrkdwn links readable
+func marshalPayload(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+// templatePayload renders the payload by the template file. The json
+// function encodes a value, such as {{json .Markdown}}, into it.
+func templatePayload(file string, data notifyData) ([]byte, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read payload template")
+	}
+	tmpl, err := template.New("payload").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := marshalPayload(v)
+			return string(b), err
+		},
+		"slack": slackText,
+	}).Parse(string(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", file)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, errors.Wrapf(err, "failed to render %s", file)
+	}
+	if !json.Valid(out.Bytes()) {
+		return nil, errors.Errorf("payload rendered by %s is not JSON", file)
+	}
+	return out.Bytes(), nil
+}
+
+var (
+	mdHeadingReg = regexp.MustCompile(`(?m)^#+\s+(.*)$`)
+	mdBoldReg    = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdItemReg    = regexp.MustCompile(`(?m)^\* `)
+)
+
+// slackText converts markdown into Slack's mrkdwn, which has its own syntax
+// for links, bold text and no headings
+func slackText(md string) string {
+	str := mdLinkReg.ReplaceAllString(md, "<$2|$1>")
+	str = mdBoldReg.ReplaceAllString(str, "*$1*")
+	str = mdHeadingReg.ReplaceAllString(str, "*$1*")
+	return mdItemReg.ReplaceAllString(str, "• ")
+}
+
+// postWebhook posts the payload, retrying on network errors, rate limits and
+// server errors with exponential backoff or as Retry-After says
+func postWebhook(u string, payload []byte, retries int) error {
+	for i := 0; ; i++ {
+		wait, err := postWebhookOnce(u, payload)
+		if err == nil {
+			return nil
+		}
+		if wait < 0 || i >= retries {
+			return err
+		}
+		if wait == 0 {
+			wait = time.Second << uint(i)
+		}
+		log.Printf("%s. retrying in %s", err, wait)
+		time.Sleep(wait)
+	}
+}
+
+// postWebhookOnce returns how long to wait before retrying a failure, or a
+// negative duration when retrying doesn't help
+func postWebhookOnce(u string, payload []byte) (time.Duration, error) {
+	resp, err := httpClient.Post(u, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		// don't leak the secret URL of the webhook into logs
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return 0, errors.Wrap(err, "failed to post to the webhook")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return 0, nil
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	err = errors.Errorf("webhook responded %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	return retryWait(resp.StatusCode, resp.Header.Get("Retry-After")), err
+}
+
+// maxRetryWait caps Retry-After, not to sleep for hours as a server says
+const maxRetryWait = time.Minute
+
+func retryWait(status int, retryAfter string) time.Duration {
+	switch {
+	case status == http.StatusTooManyRequests || status >= 500:
+		if sec, err := strconv.Atoi(retryAfter); err == nil && sec > 0 {
+			if wait := time.Duration(sec) * time.Second; wait < maxRetryWait {
+				return wait
+			}
+			return maxRetryWait
+		}
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,87 @@
+package ghch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWebhookKindOf(t *testing.T) {
+	for u, want := range map[string]string{
+		"https://hooks.slack.com/services/T0/B0/xxx":             webhookSlack,
+		"https://example.webhook.office.com/webhookb2/xxx":       webhookTeams,
+		"https://outlook.office.com/webhook/xxx":                 webhookTeams,
+		"https://ci.example.com/hooks/release":                   webhookGeneric,
+		"https://hooks.slack.com.example.com/services/T0/B0/xxx": webhookGeneric,
+	} {
+		if got := webhookKindOf(u); got != want {
+			t.Errorf("webhookKindOf(%q) = %q, want %q", u, got, want)
+		}
+	}
+}
+
+func TestSlackText(t *testing.T) {
+	md := "## [v1.0.0](https://github.com/o/r/releases/tag/v1.0.0) (2016-05-01)\n\n" +
+		"* **Component**: Add --all [#1](https://github.com/o/r/pull/1)"
+	expect := "*<https://github.com/o/r/releases/tag/v1.0.0|v1.0.0> (2016-05-01)*\n\n" +
+		"• *Component*: Add --all <https://github.com/o/r/pull/1|#1>"
+	if got := slackText(md); got != expect {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expect)
+	}
+}
+
+func TestWebhookPayload(t *testing.T) {
+	data := notifyData{Section: Section{ToRevision: "v1.0.0"}, Title: "o/r v1.0.0", Markdown: "## v1.0.0"}
+	for _, kind := range []string{webhookSlack, webhookTeams, webhookGeneric} {
+		b, err := webhookPayload(kind, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Errorf("%s: invalid payload %s", kind, b)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "ghch-notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "payload.json")
+	ioutil.WriteFile(file, []byte(`{"content": {{json .Markdown}}, "version": "{{.ToRevision}}"}`), 0644)
+	b, err := templatePayload(file, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != `{"content": "## v1.0.0", "version": "v1.0.0"}` {
+		t.Errorf("got %s", got)
+	}
+	ioutil.WriteFile(file, []byte(`{"content": {{.Markdown}}}`), 0644)
+	if _, err := templatePayload(file, data); err == nil {
+		t.Error("a payload which is not JSON should be an error")
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		expect     time.Duration
+	}{
+		{429, "30", 30 * time.Second},
+		{429, "86400", maxRetryWait},
+		{429, "", 0},
+		{503, "", 0},
+		{404, "", -1},
+		{400, "10", -1},
+	}
+	for _, tt := range tests {
+		if got := retryWait(tt.status, tt.retryAfter); got != tt.expect {
+			t.Errorf("retryWait(%d, %q) = %s, want %s", tt.status, tt.retryAfter, got, tt.expect)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package ghch
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/octokit/go-octokit/octokit"
+	"github.com/pkg/errors"
+)
+
+// offlineNotice is printed when pull requests are built from commits only
+const offlineNotice = "pull requests are built from merge and squash commits without the GitHub API: " +
+	"labels, rules by labels or paths, and logins of squash merges are not available. " +
+	"set GITHUB_TOKEN or --token for the full output"
+
+// validOffline rejects options which can't work without the API
+func validOffline(opts *ghOpts) error {
+	switch {
+	case opts.Compare:
+		return errors.New("--compare needs the GitHub API and can't be combined with --offline")
+	case opts.Milestone != "":
+		return errors.New("--milestone needs the GitHub API and can't be combined with --offline")
+	case opts.VersionsOf == versionsFromReleases:
+		return errors.New("--versions-from=releases needs the GitHub API and can't be combined with --offline")
+	case opts.GitHubNotes != "":
+		return errors.New("--github-notes needs the GitHub API and can't be combined with --offline")
+	case opts.Reactions:
+		return errors.New("--reactions needs the GitHub API and can't be combined with --offline")
+	case opts.Upstream:
+		return errors.New("--upstream needs the GitHub API and can't be combined with --offline")
+	}
+	return nil
+}
+
+// isSSHRemote reports whether the remote is reached by SSH, where git works
+// without a token while the API of a private repository doesn't
+func (gh *ghch) isSSHRemote() bool {
+	out, err := gh.cmd("config", "--get", "remote."+gh.getRemote()+".url")
+	if err != nil {
+		return false
+	}
+	u := strings.TrimSpace(out)
+	return strings.HasPrefix(u, "ssh://") || !strings.Contains(u, "://") && strings.Contains(u, "@")
+}
+
+func parseSquashSubject(subject string) []int {
+	if m := squashReg.FindStringSubmatch(subject); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return []int{n}
+	}
+	return nil
+}
+
+// squashPRNums picks pull requests up from subjects of squash merges in the
+// revisions given to `git log`, which only merge commits tell otherwise.
+// Without the API, they can't be found by --refs of other pull requests.
+func (gh *ghch) squashPRNums(revs ...string) []int {
+	out, err := gh.cmd(append([]string{"log", "--no-merges", "--format=%x1e%H%x00%s"}, revs...)...)
+	if err != nil {
+		return nil
+	}
+	return gh.recordPRCommits(out, parseSquashSubject)
+}
+
+// offlineCommit is what a merge or squash commit tells about its pull request
+type offlineCommit struct {
+	SHA      string
+	Title    string
+	Login    string
+	Name     string
+	Email    string
+	MergedAt time.Time
+}
+
+var (
+	mergeFromReg = regexp.MustCompile(`^Merge pull request #([0-9]+) from ([^/\s]+)(?:/(\S+))?`)
+	squashReg    = regexp.MustCompile(`^(.+?) \(#([0-9]+)\)$`)
+)
+
+// parseOfflineCommits reads `git log` of "%x1e%H%x00%P%x00%aN%x00%aE%x00%cI%x00%B"
+// newest first. Merge commits take the title from their body, and the login
+// from the branch of a fork. Branches of owner itself tell nothing about the
+// author, so their merges take the git author of the merged branch as squash
+// merges take their own.
+func parseOfflineCommits(out, owner string) map[int]*offlineCommit {
+	type author struct{ name, email string }
+	authors := make(map[string]author)
+	var merges []*offlineCommit
+	var mergedTips []string
+	commits := make(map[int]*offlineCommit)
+	for _, rec := range strings.Split(normalizeNewlines(out), "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(rec), "\x00", 6)
+		if len(fields) < 6 {
+			continue
+		}
+		authors[fields[0]] = author{fields[2], fields[3]}
+		lines := strings.Split(strings.TrimSpace(fields[5]), "\n")
+		subject := strings.TrimSpace(lines[0])
+		at, _ := time.Parse(time.RFC3339, fields[4])
+		c := &offlineCommit{SHA: fields[0], MergedAt: at}
+		var num int
+		if parents := strings.Fields(fields[1]); len(parents) > 1 {
+			m := mergeFromReg.FindStringSubmatch(subject)
+			if m == nil {
+				continue
+			}
+			num, _ = strconv.Atoi(m[1])
+			c.Title = m[3]
+			if strings.EqualFold(m[2], owner) {
+				merges, mergedTips = append(merges, c), append(mergedTips, parents[1])
+			} else {
+				c.Login = m[2]
+			}
+			for _, l := range lines[1:] {
+				if l = strings.TrimSpace(l); l != "" {
+					c.Title = l
+					break
+				}
+			}
+		} else if m := squashReg.FindStringSubmatch(subject); m != nil {
+			num, _ = strconv.Atoi(m[2])
+			c.Title, c.Name, c.Email = m[1], fields[2], fields[3]
+		} else {
+			continue
+		}
+		if _, ok := commits[num]; !ok {
+			commits[num] = c
+		}
+	}
+	for i, c := range merges {
+		a := authors[mergedTips[i]]
+		c.Name, c.Email = a.name, a.email
+	}
+	return commits
+}
+
+// offlinePullRequests builds the pull requests of nums from their commits.
+// Those of released sections in the cache are taken from there as they are.
+func (gh *ghch) offlinePullRequests(nums []int, released bool) (prs []*PullRequest) {
+	if gh.offlineCommits == nil {
+		out, err := gh.cmd("log", "--format=%x1e%H%x00%P%x00%aN%x00%aE%x00%cI%x00%B", gh.head(), "--tags")
+		if err != nil {
+			gh.fail(errors.Wrap(err, "failed to read merge commits. `git log` failed"))
+			return nil
+		}
+		owner, _ := gh.ownerAndRepo()
+		gh.offlineCommits = parseOfflineCommits(out, owner)
+	}
+	owner, repo := gh.ownerAndRepo()
+	for _, num := range nums {
+		if gh.excludePRs[num] {
+			continue
+		}
+		if released {
+			if pr := gh.loadCachedPR(owner, repo, num); pr != nil {
+				prs = append(prs, pr)
+				continue
+			}
+		}
+		c, ok := gh.offlineCommits[num]
+		if !ok {
+			// only referenced in commit bodies, such as of imported commits
+			if gh.verbose {
+				log.Printf("#%d has no merge or squash commit and is left out offline", num)
+			}
+			continue
+		}
+		// git authors are no logins unless the aliases say so
+		login, author := c.Login, ""
+		if login == "" {
+			var ok bool
+			if login, ok = gh.config.aliasOf(c.Name, c.Email); !ok {
+				author = c.Name
+			}
+		}
+		merged := c.MergedAt
+		prs = append(prs, &PullRequest{PullRequest: &octokit.PullRequest{
+			Number:         num,
+			Title:          c.Title,
+			HTMLURL:        fmt.Sprintf("%s/%s/%s/pull/%d", gh.getWebURL(), owner, repo, num),
+			User:           octokit.User{Login: login},
+			MergedAt:       &merged,
+			MergeCommitSha: c.SHA,
+		}, Author: author})
+	}
+	return prs
+}
@@ -0,0 +1,125 @@
+package ghch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Songmu/ghch/ghchtest"
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestParseOfflineCommits(t *testing.T) {
+	out := "\x1em4\x00c3 b4\x00Songmu\x00songmu@example.com\x002024-06-04T10:00:00Z\x00Merge pull request #4 from Songmu/cleanup\n\nClean up\n" +
+		"\x1eb4\x00c3\x00Erin Smith\x00erin@example.com\x002024-06-04T09:00:00Z\x00clean up\n" +
+		"\x1ec3\x00c2\x00Alice\x00alice@example.com\x002024-06-03T10:00:00Z\x00Add feature (#5)\n\nbody\n" +
+		"\x1em2\x00m1 b2\x00GitHub\x00noreply@github.com\x002024-06-02T10:00:00Z\x00Merge pull request #2 from bob/fix-typo\n\nFix typo in README\n" +
+		"\x1em1\x00root b1\x00GitHub\x00noreply@github.com\x002024-06-01T10:00:00Z\x00Merge pull request #1 from carol/init\n" +
+		"\x1eroot\x00\x00Dave\x00dave@example.com\x002024-05-31T10:00:00Z\x00initial commit\n"
+	expect := map[int]*offlineCommit{
+		// a branch of the owner itself is authored by the commits merged
+		4: {SHA: "m4", Title: "Clean up", Name: "Erin Smith", Email: "erin@example.com",
+			MergedAt: time.Date(2024, 6, 4, 10, 0, 0, 0, time.UTC)},
+		5: {SHA: "c3", Title: "Add feature", Name: "Alice", Email: "alice@example.com",
+			MergedAt: time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)},
+		2: {SHA: "m2", Title: "Fix typo in README", Login: "bob",
+			MergedAt: time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC)},
+		// without a body, the branch is all we know
+		1: {SHA: "m1", Title: "init", Login: "carol",
+			MergedAt: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	if got := parseOfflineCommits(out, "Songmu"); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %+v, expect %+v", got, expect)
+	}
+}
+
+func TestValidOffline(t *testing.T) {
+	if err := validOffline(&ghOpts{}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := validOffline(&ghOpts{Milestone: "v1"}); err == nil {
+		t.Error("--milestone must need the API")
+	}
+}
+
+func TestRenderDegradedSection(t *testing.T) {
+	r := &renderer{tmpl: mdTmpl}
+	s := Section{
+		ToRevision: "v1.0.0",
+		Owner:      "Songmu",
+		Repo:       "ghch",
+		Degraded:   true,
+		ChangedAt:  time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC),
+		PullRequests: []*PullRequest{{PullRequest: &octokit.PullRequest{
+			Number: 2, Title: "Fix typo", User: octokit.User{Login: "bob"},
+		}}, {PullRequest: &octokit.PullRequest{Number: 4, Title: "Clean up"}, Author: "Erin Smith"}},
+	}
+	got, err := r.section(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `## [v1.0.0](https://github.com/Songmu/ghch/releases/tag/v1.0.0) (2024-06-03)
+
+_Built from git history only: titles and authors are inferred from merge and squash commits._
+
+* Fix typo [#2](https://github.com/Songmu/ghch/pull/2) ([bob](https://github.com/bob))
+* Clean up [#4](https://github.com/Songmu/ghch/pull/4) (Erin Smith)`
+	if got != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", got, expect)
+	}
+}
+
+func TestOfflinePullRequests(t *testing.T) {
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	repo.MergePR(1, "alice", "Add a feature")
+	repo.MergePR(2, "Songmu", "Fix a crash")
+	for _, tc := range []struct {
+		aliases       map[string]string
+		login, author string
+	}{
+		{nil, "", "ghchtest"},
+		{map[string]string{"ghchtest@example.com": "songmu"}, "songmu", ""},
+	} {
+		gh := (&ghch{repoPath: repo.Dir, gitPath: "git", offline: true, config: &config{Aliases: tc.aliases}}).initialize()
+		prs := gh.offlinePullRequests([]int{1, 2}, false)
+		if len(prs) != 2 || prs[0].User.Login != "alice" || prs[0].Author != "" {
+			t.Fatalf("merges from forks should be by their owners: %+v", prs)
+		}
+		if prs[1].User.Login != tc.login || prs[1].Author != tc.author {
+			t.Errorf("got login %q and author %q, expect %q and %q", prs[1].User.Login, prs[1].Author, tc.login, tc.author)
+		}
+	}
+}
+
+func TestOfflineSection(t *testing.T) {
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	repo.MergePR(1, "alice", "Add a feature")
+	repo.SquashPR(2, "bob", "Fix a crash")
+	repo.Git("commit", "-q", "--allow-empty", "-m", "Import the old parser", "-m", "originally (#9) of the old repository")
+
+	for _, scanRefs := range []bool{false, true} {
+		gh := (&ghch{repoPath: repo.Dir, gitPath: "git", offline: true, scanRefs: scanRefs, config: &config{}}).initialize()
+		var got []int
+		for _, pr := range gh.getSection("", "").PullRequests {
+			got = append(got, pr.Number)
+		}
+		if !reflect.DeepEqual(got, []int{1, 2}) {
+			t.Errorf("refs=%v: squash merges should be found by their subjects, got %v", scanRefs, got)
+		}
+		if nums := gh.sectionPRNums([]string{""}); len(nums) != 1 || !containsNum(nums[0], 2) {
+			t.Errorf("refs=%v: squash merges should be found in the whole history, got %v", scanRefs, nums)
+		}
+		if code := gh.exitCode(false, false); code != exitCodeOK {
+			t.Errorf("refs=%v: references only in bodies should not make the output partial, got exit code %d", scanRefs, code)
+		}
+	}
+}
+
+func containsNum(nums []int, n int) bool {
+	for _, num := range nums {
+		if num == n {
+			return true
+		}
+	}
+	return false
+}
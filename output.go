@@ -0,0 +1,86 @@
+package ghch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// outputFiles are the files written into --output-dir per format
+var outputFiles = map[string]string{
+	"markdown": "CHANGELOG.md",
+	"json":     "changelog.json",
+	"html":     "changelog.html",
+}
+
+// splitFormats splits comma separated formats of --format
+func splitFormats(format string) []string {
+	var formats []string
+	for _, f := range strings.Split(format, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+func validOutputFormats(formats []string, outputDir string) error {
+	if outputDir == "" {
+		return errors.New("multiple formats need --output-dir to write each of them into")
+	}
+	for _, f := range formats {
+		if _, ok := outputFiles[f]; !ok {
+			return errors.Errorf("format %q can't be written into --output-dir: must be markdown, json or html", f)
+		}
+	}
+	return nil
+}
+
+// writeOutputs renders changes once fetched in each of the formats into
+// --output-dir
+func (cli *CLI) writeOutputs(gh *ghch, rdr *renderer, opts *ghOpts, formats []string) int {
+	var chlog Changelog
+	if opts.All {
+		chlog = gh.getChangelog(opts.NextVersion)
+	} else {
+		chlog = Changelog{Sections: []Section{gh.getCurrentSection(opts.From, opts.To, opts.NextVersion)}}
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		log.Print(errors.Wrap(err, "failed to create output directory"))
+		return exitCodeErr
+	}
+	for _, f := range formats {
+		b, err := renderOutput(rdr, chlog, f, opts.All)
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(opts.OutputDir, outputFiles[f]), b, 0644)
+		}
+		if err != nil {
+			log.Print(errors.Wrapf(err, "failed to write %s", outputFiles[f]))
+			return exitCodeErr
+		}
+	}
+	return gh.exitCode(chlog.isEmpty(), opts.ExitCode || opts.Quiet)
+}
+
+// renderOutput renders chlog in the format as it is printed without
+// --output-dir
+func renderOutput(rdr *renderer, chlog Changelog, format string, all bool) ([]byte, error) {
+	switch format {
+	case "markdown":
+		str, err := rdr.changelog(chlog)
+		return []byte(str + "\n"), err
+	case "html":
+		return renderHTMLChangelog(chlog)
+	}
+	var v interface{} = chlog
+	if !all {
+		v = chlog.Sections[0]
+	}
+	jsn, err := json.MarshalIndent(v, "", "  ")
+	return append(jsn, '\n'), err
+}
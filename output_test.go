@@ -0,0 +1,39 @@
+package ghch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitFormats(t *testing.T) {
+	if got, expect := splitFormats("markdown, json,,html"), []string{"markdown", "json", "html"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+	if err := validOutputFormats([]string{"markdown", "json"}, ""); err == nil {
+		t.Error("multiple formats without --output-dir should be an error")
+	}
+	if err := validOutputFormats([]string{"markdown", "badge"}, "out"); err == nil {
+		t.Error("badge should not be written into --output-dir")
+	}
+}
+
+func TestRenderOutput(t *testing.T) {
+	chlog := Changelog{Sections: []Section{{ToRevision: "v0.2.0", Owner: "o", Repo: "r"}}}
+	rdr := &renderer{tmpl: mdTmpl}
+	expects := map[string]string{
+		"markdown": "## [v0.2.0](https://github.com/o/r/releases/tag/v0.2.0)",
+		"json":     `"to_revision": "v0.2.0"`,
+		"html":     `<section id="v0-2-0">`,
+	}
+	for format, expect := range expects {
+		b, err := renderOutput(rdr, chlog, format, false)
+		if err != nil {
+			t.Errorf("%s: %s", format, err)
+			continue
+		}
+		if !strings.Contains(string(b), expect) {
+			t.Errorf("%s: %q should contain %q", format, string(b), expect)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ghch
+
+import (
+	"sort"
+	"time"
+)
+
+const provenanceSchema = "ghch/provenance/v1"
+
+// provenance maps each version to the merge commits, pull requests and
+// authors it shipped, for compliance and provenance systems to trace
+// releases. Output of --format=provenance-json.
+type provenance struct {
+	Schema     string              `json:"schema"`
+	Repository string              `json:"repository"`
+	Versions   []provenanceVersion `json:"versions"`
+}
+
+type provenanceVersion struct {
+	Version         string             `json:"version"`
+	PreviousVersion string             `json:"previous_version,omitempty"`
+	Commit          string             `json:"commit,omitempty"`
+	ReleasedAt      time.Time          `json:"released_at"`
+	PullRequests    []provenancePR     `json:"pull_requests"`
+	DirectCommits   []provenanceCommit `json:"direct_commits,omitempty"`
+}
+
+type provenancePR struct {
+	Number      int        `json:"number"`
+	URL         string     `json:"url"`
+	MergeCommit string     `json:"merge_commit,omitempty"`
+	Author      string     `json:"author"`
+	MergedAt    *time.Time `json:"merged_at,omitempty"`
+}
+
+type provenanceCommit struct {
+	Commit string `json:"commit"`
+	Author string `json:"author"`
+}
+
+func (gh *ghch) provenance(chlog Changelog) provenance {
+	return buildProvenance(chlog, func(rev string) string {
+		if rev == "" {
+			rev = gh.head()
+		}
+		return gh.commitOf(gh.localRevision(rev))
+	}, gh.prCommits)
+}
+
+// buildProvenance resolves revisions to full SHAs by commitOf. Merge commits
+// found in the local history take precedence over those GitHub reports.
+func buildProvenance(chlog Changelog, commitOf func(string) string, prCommits map[int]string) provenance {
+	p := provenance{Schema: provenanceSchema, Versions: []provenanceVersion{}}
+	for _, s := range chlog.Sections {
+		if p.Repository == "" && s.Owner != "" {
+			p.Repository = repoURL(s)
+		}
+		v := provenanceVersion{
+			Version:         s.ToRevision,
+			PreviousVersion: s.FromRevision,
+			Commit:          commitOf(s.ToRevision),
+			ReleasedAt:      s.ChangedAt,
+			PullRequests:    []provenancePR{},
+		}
+		if v.Version == "" {
+			v.Version = "unreleased"
+		}
+		for _, pr := range s.PullRequests {
+			if pr.PullRequest == nil {
+				continue
+			}
+			item := provenancePR{
+				Number:      pr.Number,
+				URL:         pr.HTMLURL,
+				MergeCommit: prCommits[pr.Number],
+				Author:      pr.User.Login,
+				MergedAt:    pr.MergedAt,
+			}
+			if item.URL == "" {
+				item.URL = prURL(s, pr.Number)
+			}
+			if item.MergeCommit == "" {
+				item.MergeCommit = pr.MergeCommitSha
+			}
+			v.PullRequests = append(v.PullRequests, item)
+		}
+		sort.Slice(v.PullRequests, func(i, j int) bool {
+			return v.PullRequests[i].Number < v.PullRequests[j].Number
+		})
+		for _, c := range s.DirectCommits {
+			sha := commitOf(c.SHA)
+			if sha == "" {
+				sha = c.SHA
+			}
+			v.DirectCommits = append(v.DirectCommits, provenanceCommit{Commit: sha, Author: c.Author})
+		}
+		p.Versions = append(p.Versions, v)
+	}
+	return p
+}
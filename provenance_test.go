@@ -0,0 +1,52 @@
+package ghch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestBuildProvenance(t *testing.T) {
+	at := time.Date(2016, 5, 1, 0, 0, 0, 0, time.UTC)
+	pr := func(num int, sha string) *PullRequest {
+		return &PullRequest{PullRequest: &octokit.PullRequest{Number: num, User: octokit.User{Login: "Songmu"}, MergeCommitSha: sha}}
+	}
+	chlog := Changelog{Sections: []Section{
+		{
+			FromRevision:  "v0.1.0",
+			Owner:         "o",
+			Repo:          "r",
+			PullRequests:  []*PullRequest{pr(3, "squashed3"), pr(2, "squashed2")},
+			DirectCommits: []*Commit{{SHA: "abc1234", Author: "Songmu"}},
+		},
+		{ToRevision: "v0.1.0", Owner: "o", Repo: "r", ChangedAt: at},
+	}}
+	shas := map[string]string{"": "head000", "v0.1.0": "tag0001", "abc1234": "abc1234full"}
+	got := buildProvenance(chlog, func(rev string) string { return shas[rev] }, map[int]string{2: "merge002"})
+
+	if got.Schema != provenanceSchema || got.Repository != "https://github.com/o/r" {
+		t.Errorf("got %+v", got)
+	}
+	if len(got.Versions) != 2 {
+		t.Fatalf("got %+v", got.Versions)
+	}
+	v := got.Versions[0]
+	if v.Version != "unreleased" || v.Commit != "head000" || v.PreviousVersion != "v0.1.0" {
+		t.Errorf("got %+v", v)
+	}
+	expect := []provenancePR{
+		{Number: 2, URL: "https://github.com/o/r/pull/2", MergeCommit: "merge002", Author: "Songmu"},
+		{Number: 3, URL: "https://github.com/o/r/pull/3", MergeCommit: "squashed3", Author: "Songmu"},
+	}
+	if !reflect.DeepEqual(v.PullRequests, expect) {
+		t.Errorf("got %+v, want %+v", v.PullRequests, expect)
+	}
+	if len(v.DirectCommits) != 1 || v.DirectCommits[0].Commit != "abc1234full" {
+		t.Errorf("got %+v", v.DirectCommits)
+	}
+	if r := got.Versions[1]; r.Commit != "tag0001" || !r.ReleasedAt.Equal(at) || r.PullRequests == nil {
+		t.Errorf("got %+v", r)
+	}
+}
@@ -0,0 +1,39 @@
+package ghch
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// fetchReactions sets 👍 reactions and comments of the pull request, which
+// keep changing and are never cached
+func (gh *ghch) fetchReactions(owner, repo string, pr *PullRequest) error {
+	var issue struct {
+		Comments  int `json:"comments"`
+		Reactions struct {
+			ThumbsUp int `json:"+1"`
+		} `json:"reactions"`
+	}
+	path := fmt.Sprintf("repos/%s/%s/issues/%d", owner, repo, pr.Number)
+	if _, err := gh.apiGet(path, &issue); err != nil {
+		return errors.Wrapf(err, "failed to fetch reactions of #%d", pr.Number)
+	}
+	pr.ThumbsUp = issue.Reactions.ThumbsUp
+	pr.Comments = issue.Comments
+	return nil
+}
+
+// sortByReactions orders pull requests by their 👍 reactions, then comments,
+// keeping the order of ties
+func sortByReactions(prs []*PullRequest) []*PullRequest {
+	sorted := append([]*PullRequest{}, prs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ThumbsUp != sorted[j].ThumbsUp {
+			return sorted[i].ThumbsUp > sorted[j].ThumbsUp
+		}
+		return sorted[i].Comments > sorted[j].Comments
+	})
+	return sorted
+}
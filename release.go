@@ -0,0 +1,148 @@
+package ghch
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/octokit/go-octokit/octokit"
+	"github.com/pkg/errors"
+)
+
+type releaseOpts struct {
+	ghOpts
+	Draft      bool `long:"draft" description:"create the release as a draft"`
+	Prerelease bool `long:"prerelease" description:"mark the release as a prerelease"`
+}
+
+func (cli *CLI) runRelease(argv []string) int {
+	opts := &releaseOpts{}
+	p, _, err := parseCommandArgs("release", opts, argv)
+	if err != nil {
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+
+	opts.editsOutput = true
+	gh, err := opts.newGhch()
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	rdr, err := opts.newRenderer(gh.config)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	params, err := gh.releaseParams(rdr, &opts.ghOpts)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	params.Draft, params.Prerelease = opts.Draft, opts.Prerelease
+	if opts.Edit {
+		if params.Body, err = editText(params.Body); err != nil {
+			log.Print(err)
+			return exitCodeErr
+		}
+	}
+
+	if params.TargetCommitish != "" && !gh.isTagPushed(params.TagName) {
+		log.Printf("tag %s is not pushed to %s. GitHub creates it on %s", params.TagName, gh.getRemote(), params.TargetCommitish)
+	}
+
+	owner, repo := gh.ownerAndRepo()
+	if opts.DryRun {
+		fmt.Fprintf(cli.OutStream, "would create release %s on %s/%s%s\n\n%s\n",
+			params.TagName, owner, repo, releaseFlags(params), params.Body)
+		return gh.exitCode(false, false)
+	}
+	url, err := octokit.ReleasesURL.Expand(octokit.M{"owner": owner, "repo": repo})
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	rel, r := gh.client.Releases(url).Create(params)
+	if r.HasError() {
+		log.Print(errors.Wrap(r.Err, "failed to create release"))
+		return exitCodeErr
+	}
+	fmt.Fprintln(cli.OutStream, rel.HTMLURL)
+	return gh.exitCode(false, false)
+}
+
+// releaseParams builds the release of --next-version (a tag to be created on
+// the target branch) or of the latest version tag
+func (gh *ghch) releaseParams(rdr *renderer, opts *ghOpts) (octokit.ReleaseParams, error) {
+	from, to, tag, err := gh.releaseRange(opts)
+	if err != nil {
+		return octokit.ReleaseParams{}, err
+	}
+	s := gh.getCurrentSection(from, to, tag)
+	body, err := rdr.section(s)
+	if err != nil {
+		return octokit.ReleaseParams{}, err
+	}
+	params := octokit.ReleaseParams{
+		TagName: tag,
+		Name:    tag,
+		Body:    body,
+	}
+	if to == "" {
+		// the tag does not exist yet and GitHub creates it on the commit
+		sha, err := gh.cmd("rev-parse", gh.head())
+		if err != nil {
+			return octokit.ReleaseParams{}, errors.Wrap(err, "failed to resolve target commit. `git rev-parse` failed")
+		}
+		params.TargetCommitish = strings.TrimSpace(sha)
+	} else if t, ok := gh.tagRefs()[to]; ok {
+		// GitHub ignores the target of a pushed tag, while it would create an
+		// unpushed one on the default branch without it
+		params.TargetCommitish = t.SHA
+	}
+	return params, nil
+}
+
+// releaseRange returns the range of --next-version, or of the latest version
+// tag, and the version released
+func (gh *ghch) releaseRange(opts *ghOpts) (from, to, tag string, err error) {
+	from, to, tag = opts.From, opts.To, opts.NextVersion
+	if from == "" && to == "" && tag == "" {
+		vers := gh.versions()
+		if len(vers) < 1 {
+			return "", "", "", errors.New("no version tag to release. specify --next-version")
+		}
+		to = vers[0]
+		if len(vers) > 1 {
+			from = vers[1]
+		}
+	}
+	if tag == "" {
+		tag = to
+	}
+	if tag == "" {
+		return "", "", "", errors.New("no tag to release. specify --to or --next-version")
+	}
+	return from, to, tag, nil
+}
+
+// isTagPushed reports whether the remote has the tag. It is taken as pushed
+// when the remote can't be reached, not to warn for nothing.
+func (gh *ghch) isTagPushed(tag string) bool {
+	out, err := gh.cmd("ls-remote", "--tags", gh.getRemote(), "refs/tags/"+tag)
+	return err != nil || strings.TrimSpace(out) != ""
+}
+
+func releaseFlags(params octokit.ReleaseParams) string {
+	var fl []string
+	if params.Draft {
+		fl = append(fl, "draft")
+	}
+	if params.Prerelease {
+		fl = append(fl, "prerelease")
+	}
+	if len(fl) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(fl, ", ") + ")"
+}
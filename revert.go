@@ -0,0 +1,125 @@
+package ghch
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	revertsDrop     = "drop"
+	revertsAnnotate = "annotate"
+)
+
+var (
+	revertTitleReg = regexp.MustCompile(`^Revert "(.+)"$`)
+	// the body of pull requests made by the revert button of GitHub
+	revertBodyReg = regexp.MustCompile(`(?m)^Reverts [^\s#]+#([0-9]+)`)
+)
+
+// revertedTitle returns what the title reverts, if it is a revert
+func revertedTitle(title string) (string, bool) {
+	if matches := revertTitleReg.FindStringSubmatch(title); len(matches) > 1 {
+		return matches[1], true
+	}
+	return "", false
+}
+
+// handleReverts pairs changes with their reverts in the same section, then
+// drops or annotates both of them according to mode
+func (rs *Section) handleReverts(mode string) {
+	if mode != revertsDrop && mode != revertsAnnotate {
+		return
+	}
+	dropped := make(map[*PullRequest]bool)
+	for _, p := range pairRevertedPRs(rs.PullRequests) {
+		if mode == revertsDrop {
+			dropped[p[0]], dropped[p[1]] = true, true
+		} else {
+			p[0].RevertedBy, p[1].Reverts = p[1].Number, p[0].Number
+		}
+	}
+	if len(dropped) > 0 {
+		rs.removePRs(func(pr *PullRequest) bool { return dropped[pr] })
+	}
+
+	droppedCommits := make(map[*Commit]bool)
+	for _, p := range pairRevertedCommits(rs.DirectCommits) {
+		if mode == revertsDrop {
+			droppedCommits[p[0]], droppedCommits[p[1]] = true, true
+		} else {
+			p[0].RevertedBy, p[1].Reverts = p[1].SHA, p[0].SHA
+		}
+	}
+	if len(droppedCommits) > 0 {
+		var commits []*Commit
+		for _, c := range rs.DirectCommits {
+			if !droppedCommits[c] {
+				commits = append(commits, c)
+			}
+		}
+		rs.DirectCommits = commits
+	}
+}
+
+// pairRevertedPRs returns pairs of a pull request and the one reverting it.
+// Later reverts are paired first, so that reverting a revert brings the
+// original change back.
+func pairRevertedPRs(prs []*PullRequest) (pairs [][2]*PullRequest) {
+	sorted := append([]*PullRequest{}, prs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return mergedAfter(sorted[i], sorted[j])
+	})
+	paired := make(map[*PullRequest]bool)
+	for _, rev := range sorted {
+		if paired[rev] {
+			continue
+		}
+		title, ok := revertedTitle(rev.Title)
+		num := 0
+		if matches := revertBodyReg.FindStringSubmatch(rev.Body); len(matches) > 1 {
+			num, _ = strconv.Atoi(matches[1])
+		}
+		if !ok && num == 0 {
+			continue
+		}
+		for _, pr := range sorted {
+			if pr == rev || paired[pr] {
+				continue
+			}
+			if (num != 0 && pr.Number == num) || (num == 0 && pr.Title == title) {
+				paired[pr], paired[rev] = true, true
+				pairs = append(pairs, [2]*PullRequest{pr, rev})
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+func mergedAfter(a, b *PullRequest) bool {
+	if a.MergedAt == nil || b.MergedAt == nil {
+		return a.Number > b.Number
+	}
+	return a.MergedAt.After(*b.MergedAt)
+}
+
+// pairRevertedCommits returns pairs of a commit and the one reverting it.
+// Commits are listed newest first like `git log`.
+func pairRevertedCommits(commits []*Commit) (pairs [][2]*Commit) {
+	paired := make(map[*Commit]bool)
+	for i, rev := range commits {
+		subject, ok := revertedTitle(rev.Subject)
+		if !ok || paired[rev] {
+			continue
+		}
+		for _, c := range commits[i+1:] {
+			if !paired[c] && c.Subject == subject {
+				paired[c], paired[rev] = true, true
+				pairs = append(pairs, [2]*Commit{c, rev})
+				break
+			}
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,53 @@
+package ghch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestHandleReverts(t *testing.T) {
+	newSection := func() Section {
+		pr := func(num int, title, body string, day int) *PullRequest {
+			at := time.Date(2016, 4, day, 0, 0, 0, 0, time.UTC)
+			return &PullRequest{PullRequest: &octokit.PullRequest{Number: num, Title: title, Body: body, MergedAt: &at}}
+		}
+		return Section{
+			PullRequests: []*PullRequest{
+				pr(4, `Revert "Revert "Add bar""`, "", 4),
+				pr(3, `Revert "Add bar"`, "", 3),
+				pr(2, `Revert "Add foo"`, "Reverts Songmu/ghch#1\n\nbroke the build", 2),
+				pr(1, "Add foo", "", 1),
+				pr(5, "Add bar", "", 1),
+			},
+			DirectCommits: []*Commit{
+				{SHA: "ccc", Subject: `Revert "fix typo"`},
+				{SHA: "bbb", Subject: "fix typo"},
+				{SHA: "aaa", Subject: "update docs"},
+			},
+		}
+	}
+
+	s := newSection()
+	s.handleReverts(revertsDrop)
+	if len(s.PullRequests) != 1 || s.PullRequests[0].Number != 5 {
+		t.Errorf("only #5 should remain: %+v", s.PullRequests)
+	}
+	if len(s.DirectCommits) != 1 || s.DirectCommits[0].SHA != "aaa" {
+		t.Errorf("only aaa should remain: %+v", s.DirectCommits)
+	}
+
+	s = newSection()
+	s.handleReverts(revertsAnnotate)
+	reverted := map[int]int{}
+	for _, pr := range s.PullRequests {
+		reverted[pr.Number] = pr.RevertedBy
+	}
+	if reverted[1] != 2 || reverted[3] != 4 || reverted[5] != 0 {
+		t.Errorf("unexpected annotations: %v", reverted)
+	}
+	if s.DirectCommits[1].RevertedBy != "ccc" || s.DirectCommits[0].Reverts != "bbb" {
+		t.Errorf("unexpected annotations of commits: %+v", s.DirectCommits)
+	}
+}
@@ -0,0 +1,109 @@
+package ghch
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// versionScheme decides which tags are versions and how they are ordered.
+// The latest version is the greatest one.
+type versionScheme interface {
+	// match reports whether the tag is a version of the scheme
+	match(tag string) bool
+	// less reports whether version a is older than b
+	less(a, b string) bool
+}
+
+// calver tags like 2024.06, v24.6.1 or 2024-06-15
+const calverPattern = `^v?([0-9]{2}|[0-9]{4})[._-]([0-9]{1,2})(?:[._-]([0-9]{1,2}))?(?:[._-]([0-9]+))?$`
+
+// parseVersionScheme parses --version-scheme. The default semver scheme is
+// nil, leaving tags to gitsemvers.
+func parseVersionScheme(spec string) (versionScheme, error) {
+	switch {
+	case spec == "" || spec == "semver":
+		return nil, nil
+	case spec == "calver":
+		return &regexScheme{reg: regexp.MustCompile(calverPattern)}, nil
+	case strings.HasPrefix(spec, "regex:"):
+		reg, err := regexp.Compile(strings.TrimPrefix(spec, "regex:"))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid --version-scheme pattern")
+		}
+		return &regexScheme{reg: reg}, nil
+	}
+	return nil, errors.Errorf("unknown --version-scheme %q: must be semver, calver or regex:<pattern>", spec)
+}
+
+// semverScheme orders semver tags by their precedence, like gitsemvers
+type semverScheme struct{}
+
+func (semverScheme) match(tag string) bool {
+	_, ok := parseVersion(tag)
+	return ok
+}
+
+func (semverScheme) less(a, b string) bool {
+	return compareVersions(a, b) < 0
+}
+
+// regexScheme matches versions by a pattern and orders them by its capture
+// groups from left to right, numerically when both are numbers. A pattern
+// without groups orders them by the runs of digits and others of the whole
+// tags, so that build-10 is newer than build-9.
+type regexScheme struct {
+	reg *regexp.Regexp
+}
+
+func (rs *regexScheme) match(tag string) bool {
+	return rs.reg.MatchString(tag)
+}
+
+func (rs *regexScheme) parts(tag string) []string {
+	m := rs.reg.FindStringSubmatch(tag)
+	if len(m) > 1 {
+		return m[1:]
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return digitRunReg.FindAllString(m[0], -1)
+}
+
+var digitRunReg = regexp.MustCompile(`[0-9]+|[^0-9]+`)
+
+func (rs *regexScheme) less(a, b string) bool {
+	pa, pb := rs.parts(a), rs.parts(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] == pb[i] {
+			continue
+		}
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		if errA == nil && errB == nil {
+			return na < nb
+		}
+		// a missing part like the micro of 2024.06 goes first
+		return pa[i] < pb[i]
+	}
+	return len(pa) < len(pb)
+}
+
+// schemeVersions returns the tags which are versions of the scheme, newest
+// first
+func schemeVersions(scheme versionScheme, tags []string) []string {
+	var vers []string
+	for _, t := range tags {
+		if scheme.match(t) {
+			vers = append(vers, t)
+		}
+	}
+	sort.SliceStable(vers, func(i, j int) bool {
+		return scheme.less(vers[j], vers[i])
+	})
+	return vers
+}
@@ -0,0 +1,52 @@
+package ghch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersionScheme(t *testing.T) {
+	for _, spec := range []string{"", "semver"} {
+		if s, err := parseVersionScheme(spec); s != nil || err != nil {
+			t.Errorf("%q should leave tags to gitsemvers: %v, %v", spec, s, err)
+		}
+	}
+	for _, spec := range []string{"semantic", "regex:("} {
+		if _, err := parseVersionScheme(spec); err == nil {
+			t.Errorf("%q should be an error", spec)
+		}
+	}
+}
+
+func TestSchemeVersions(t *testing.T) {
+	tests := []struct {
+		spec   string
+		tags   []string
+		expect []string
+	}{
+		{
+			spec:   "calver",
+			tags:   []string{"2024.06", "v1.2.3", "2024.06.1", "2023.12.3", "2024.10", "latest"},
+			expect: []string{"2024.10", "2024.06.1", "2024.06", "2023.12.3"},
+		},
+		{
+			spec:   `regex:^RELEASE_(\d+)_(\d+)$`,
+			tags:   []string{"RELEASE_2024_06", "RELEASE_2023_12", "RELEASE_2024_10", "v1.0.0", "RELEASE_2024_6rc"},
+			expect: []string{"RELEASE_2024_10", "RELEASE_2024_06", "RELEASE_2023_12"},
+		},
+		{
+			spec:   `regex:^build-\d+$`,
+			tags:   []string{"build-9", "build-10", "build-1", "other"},
+			expect: []string{"build-10", "build-9", "build-1"},
+		},
+	}
+	for _, tt := range tests {
+		scheme, err := parseVersionScheme(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := schemeVersions(scheme, tt.tags); !reflect.DeepEqual(got, tt.expect) {
+			t.Errorf("%s: got %v, want %v", tt.spec, got, tt.expect)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package ghch
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fetch refreshes tags and the target branch from the remote, so that stale
+// local clones and CI caches don't miss the latest versions. The target is
+// --branch or the branch checked out, whose remote-tracking branch the range
+// is resolved against afterwards, since the local one may be behind. A
+// detached HEAD, as CI checks out, is kept as it is.
+func (gh *ghch) fetch() error {
+	remote := gh.getRemote()
+	branch := strings.TrimPrefix(gh.branch, remote+"/")
+	if branch == "" {
+		out, _ := gh.cmd("symbolic-ref", "-q", "--short", "HEAD")
+		branch = strings.TrimSpace(out)
+	}
+	arg := []string{"fetch", "--tags", remote}
+	if branch != "" {
+		arg = append(arg, fetchRefspec(remote, branch))
+	}
+	if _, err := gh.cmd(arg...); err != nil {
+		return errors.Wrap(err, "failed to fetch from the remote. `git fetch` failed")
+	}
+	if branch != "" {
+		gh.branch = remote + "/" + branch
+	}
+	return nil
+}
+
+// fetchRefspec updates the remote-tracking branch even if it was rewound
+func fetchRefspec(remote, branch string) string {
+	return "+refs/heads/" + branch + ":refs/remotes/" + remote + "/" + branch
+}
+
+func (gh *ghch) isShallow() bool {
+	out, err := gh.cmd("rev-parse", "--git-path", "shallow")
+	if err != nil {
+		return false
+	}
+	p := strings.TrimSpace(out)
+	if !filepath.IsAbs(p) && gh.gitDir == "" {
+		p = filepath.Join(gh.repoPath, p)
+	}
+	_, err = os.Stat(p)
+	return err == nil
+}
+
+// checkShallow fails on shallow clones, typical for CI checkouts, because
+// tag ranges can't be resolved on truncated history. With unshallow the
+// missing history and tags are fetched instead.
+func (gh *ghch) checkShallow(unshallow bool) error {
+	if !gh.isShallow() {
+		return nil
+	}
+	if !unshallow {
+		return errors.Errorf("the repository is a shallow clone and the history is incomplete. "+
+			"run `git fetch --unshallow --tags %s`, retry with --unshallow, "+
+			"or check out with full history (e.g. `fetch-depth: 0` of actions/checkout)", gh.getRemote())
+	}
+	log.Printf("fetching the full history from %s since the repository is a shallow clone", gh.getRemote())
+	if _, err := gh.cmd("fetch", "--unshallow", "--tags", gh.getRemote()); err != nil {
+		return errors.Wrap(err, "failed to unshallow the repository. `git fetch --unshallow` failed")
+	}
+	return nil
+}
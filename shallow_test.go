@@ -0,0 +1,110 @@
+package ghch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Songmu/ghch/ghchtest"
+)
+
+// testGit runs git in dir ignoring user and system configs, and fails the
+// test on errors
+func testGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"HOME="+dir,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=ghch", "GIT_AUTHOR_EMAIL=ghch@example.com",
+		"GIT_COMMITTER_NAME=ghch", "GIT_COMMITTER_EMAIL=ghch@example.com",
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// testOrigin creates a repository of two tagged commits to be cloned, and
+// returns its path
+func testOrigin(t *testing.T) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "ghch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	dir := filepath.Join(root, "origin")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	testGit(t, dir, "init", "-q")
+	for _, tag := range []string{"v0.1.0", "v0.2.0"} {
+		testGit(t, dir, "commit", "-q", "--allow-empty", "-m", "release "+tag)
+		testGit(t, dir, "tag", tag)
+	}
+	return dir
+}
+
+func TestCheckShallow(t *testing.T) {
+	origin := testOrigin(t)
+	root := filepath.Dir(origin)
+	testGit(t, root, "clone", "-q", "file://"+origin, "full")
+	testGit(t, root, "clone", "-q", "--depth", "1", "file://"+origin, "shallow")
+
+	testCases := []struct {
+		dir       string
+		unshallow bool
+		err       bool
+		shallow   bool
+	}{
+		{"full", false, false, false},
+		{"shallow", false, true, true},
+		{"shallow", true, false, false},
+	}
+	for _, tc := range testCases {
+		gh := &ghch{repoPath: filepath.Join(root, tc.dir), gitPath: "git"}
+		err := gh.checkShallow(tc.unshallow)
+		if tc.err != (err != nil) {
+			t.Errorf("%s(unshallow=%v): unexpected error: %v", tc.dir, tc.unshallow, err)
+		}
+		if got := gh.isShallow(); got != tc.shallow {
+			t.Errorf("%s(unshallow=%v): isShallow() = %v, expect %v", tc.dir, tc.unshallow, got, tc.shallow)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	repo.MergePR(1, "alice", "Add a feature")
+	repo.Tag("v0.1.0")
+	repo.Push()
+	stale := repo.Clone()
+	repo.MergePR(2, "bob", "Fix a crash")
+	repo.Tag("v0.2.0")
+	repo.Push()
+
+	for _, branch := range []string{"", "master", "origin/master"} {
+		gh := (&ghch{repoPath: stale.Dir, gitPath: "git", branch: branch, config: &config{}}).initialize()
+		if err := gh.fetch(); err != nil {
+			t.Fatal(err)
+		}
+		if gh.branch != "origin/master" {
+			t.Errorf("%q: the range should end at the fetched branch, got %q", branch, gh.branch)
+		}
+		if got := gh.getLatestSemverTag(); got != "v0.2.0" {
+			t.Errorf("%q: got latest version %s, expect v0.2.0", branch, got)
+		}
+		if got := gh.mergedPRNums("v0.1.0", ""); len(got) != 1 || got[0] != 2 {
+			t.Errorf("%q: got %v, expect [2]", branch, got)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package ghch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type siteOpts struct {
+	ghOpts
+	Out     string `short:"o" long:"out" default:"public" description:"output directory"`
+	BaseURL string `          long:"base-url" description:"URL the site is published at, required for the Atom feed"`
+	Title   string `          long:"title" description:"site title (default: \"<repo> changelog\")"`
+}
+
+func (cli *CLI) runSite(argv []string) int {
+	opts := &siteOpts{}
+	p, _, err := parseCommandArgs("site", opts, argv)
+	if err != nil {
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+	gh, err := opts.newGhch()
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	chlog := gh.getChangelog(opts.NextVersion)
+	if err := writeSite(opts.Out, chlog, opts.Title, opts.BaseURL); err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	return gh.exitCode(chlog.isEmpty(), opts.ExitCode || opts.Quiet)
+}
+
+// sitePage is one HTML page per release
+type sitePage struct {
+	Section
+	Title string
+	Slug  string
+}
+
+func newSitePages(chlog Changelog) []sitePage {
+	var pages []sitePage
+	for _, sec := range chlog.Sections {
+		title := sec.ToRevision
+		if title == "" {
+			// nothing to publish until something is merged after the latest release
+			if len(sec.PullRequests) == 0 && len(sec.DirectCommits) == 0 {
+				continue
+			}
+			title = "Unreleased"
+		}
+		pages = append(pages, sitePage{Section: sec, Title: title, Slug: slugify(title)})
+	}
+	return pages
+}
+
+// siteTmpl links pull requests, authors and commits by the same helpers as
+// the markdown templates, so names which are not logins stay unlinked
+var siteTmpl = template.Must(template.New("site").Funcs(template.FuncMap(tmplFuncs)).Parse(`{{define "layout"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+{{- if .Feed}}
+<link rel="alternate" type="application/atom+xml" href="feed.xml">
+{{- end}}
+<style>body{font-family:sans-serif;max-width:48em;margin:2em auto;padding:0 1em;line-height:1.5}time{color:#666}</style>
+</head>
+<body>
+{{template "body" .}}
+</body>
+</html>
+{{end}}
+{{- define "index"}}<h1>{{.Title}}</h1>
+<ul>
+{{- range .Pages}}
+<li><a href="{{.Slug}}.html">{{.Title}}</a> <time>{{.ChangedAt.Format "2006-01-02"}}</time> ({{len .PullRequests}} pull requests)</li>
+{{- end}}
+</ul>
+{{end}}
+{{- define "document"}}<h1>{{.Title}}</h1>
+{{- range .Pages}}
+<section id="{{.Slug}}">
+{{template "changes" .}}</section>
+{{- end}}
+{{end}}
+{{- define "release"}}<p><a href="index.html">{{.SiteTitle}}</a></p>
+{{template "changes" .Page}}
+{{end}}
+{{- define "changes"}}{{$sec := .Section -}}
+<h1>{{.Title}}</h1>
+<p><time>{{.ChangedAt.Format "2006-01-02"}}</time></p>
+{{- if .Highlights}}
+<h2>Highlights</h2>
+<ul>
+{{- range .Highlights}}
+{{template "item" item $sec .}}
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Categories}}
+{{- range .Categories}}
+<h2>{{.Name}}</h2>
+<ul>
+{{- range .PullRequests}}
+{{template "item" item $sec .}}
+{{- end}}
+{{- range .Extras}}
+{{template "extra" extra $sec .}}
+{{- end}}
+</ul>
+{{- end}}
+{{- else}}
+<ul>
+{{- range .PullRequests}}
+{{template "item" item $sec .}}
+{{- end}}
+{{- range .Extras}}
+{{template "extra" extra $sec .}}
+{{- end}}
+</ul>
+{{- end}}
+{{- if .DirectCommits}}
+<h2>Direct commits</h2>
+<ul>
+{{- range .DirectCommits}}
+<li>{{.Subject}} <a href="{{commitURL $sec .SHA}}">{{.SHA}}</a> ({{.Author}})</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .NewContributors}}
+<h2>New contributors</h2>
+<ul>
+{{- range .NewContributors}}
+<li>{{template "user" user $sec .Login}} made their first contribution in <a href="{{prURL $sec .PullRequest}}">#{{.PullRequest}}</a></li>
+{{- end}}
+</ul>
+{{- end}}
+{{end}}
+{{- define "item" -}}
+<li>{{with .Component}}<strong>{{.}}</strong>: {{end}}{{.Title}} <a href="{{prURL .Section .Number}}">#{{.Number}}</a> ({{if .User.Login}}{{template "user" user .Section .User.Login}}{{else}}{{.Author}}{{end}})</li>
+{{- end}}
+{{- define "extra" -}}
+<li>{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}{{with .Author}} ({{template "user" user $.Section .}}){{end}}</li>
+{{- end}}
+{{- define "user"}}{{with .URL}}<a href="{{.}}">{{$.Name}}</a>{{else}}{{.Name}}{{end}}{{end}}`))
+
+func siteTitle(chlog Changelog, title string) string {
+	if title != "" {
+		return title
+	}
+	if len(chlog.Sections) > 0 && chlog.Sections[0].Repo != "" {
+		return chlog.Sections[0].Repo + " changelog"
+	}
+	return "Changelog"
+}
+
+// writeSite renders an index, one page per release and an Atom feed into dir.
+// The feed is left out without baseURL, since feed readers need absolute IDs
+// and links.
+func writeSite(dir string, chlog Changelog, title, baseURL string) error {
+	pages := newSitePages(chlog)
+	withFeed := baseURL != ""
+	title = siteTitle(chlog, title)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create site directory")
+	}
+
+	index, err := renderSitePage("index", struct {
+		Title string
+		Feed  bool
+		Pages []sitePage
+	}{title, withFeed, pages})
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{"index.html": index}
+	for _, page := range pages {
+		b, err := renderSitePage("release", struct {
+			Title     string
+			Feed      bool
+			SiteTitle string
+			Page      sitePage
+		}{page.Title + " - " + title, withFeed, title, page})
+		if err != nil {
+			return err
+		}
+		files[page.Slug+".html"] = b
+	}
+	if withFeed {
+		feed, err := newAtomFeed(pages, title, baseURL)
+		if err != nil {
+			return err
+		}
+		files["feed.xml"] = feed
+	}
+
+	for name, b := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			return errors.Wrap(err, "failed to write site")
+		}
+	}
+	return nil
+}
+
+// renderHTMLChangelog renders the whole changelog as a single HTML page
+func renderHTMLChangelog(chlog Changelog) ([]byte, error) {
+	return renderSitePage("document", struct {
+		Title string
+		Feed  bool
+		Pages []sitePage
+	}{siteTitle(chlog, ""), false, newSitePages(chlog)})
+}
+
+func renderSitePage(name string, data interface{}) ([]byte, error) {
+	tmpl, err := siteTmpl.Clone()
+	if err == nil {
+		_, err = tmpl.New("body").Parse(`{{template "` + name + `" .}}`)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "layout", data); err != nil {
+		return nil, errors.Wrapf(err, "failed to render %s page", name)
+	}
+	return b.Bytes(), nil
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+func newAtomFeed(pages []sitePage, title, baseURL string) ([]byte, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	feed := atomFeed{
+		XMLNS: "http://www.w3.org/2005/Atom",
+		Title: title,
+		ID:    baseURL + "feed.xml",
+		Links: []atomLink{{Href: baseURL + "feed.xml", Rel: "self"}, {Href: baseURL + "index.html"}},
+	}
+	for i, page := range pages {
+		var b bytes.Buffer
+		tmpl, err := siteTmpl.Clone()
+		if err == nil {
+			err = tmpl.ExecuteTemplate(&b, "changes", page)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render feed")
+		}
+		updated := page.ChangedAt.Format(time.RFC3339)
+		if i == 0 {
+			feed.Updated = updated
+		}
+		id := baseURL + page.Slug + ".html"
+		if page.ToRevision != "" && page.Owner != "" {
+			id = releaseURL(page.Section, page.ToRevision)
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   page.Title,
+			ID:      id,
+			Updated: updated,
+			Link:    atomLink{Href: baseURL + page.Slug + ".html"},
+			Content: atomContent{Type: "html", Body: b.String()},
+		})
+	}
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render feed")
+	}
+	return append([]byte(xml.Header), b...), nil
+}
@@ -0,0 +1,117 @@
+package ghch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestWriteSite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghch-site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chlog := Changelog{Sections: []Section{{
+		ToRevision: "",
+		Owner:      "mackerelio",
+		Repo:       "mackerel-agent",
+	}, {
+		PullRequests: []*PullRequest{{PullRequest: &octokit.PullRequest{
+			Title:   "<script>alert(1)</script>",
+			Number:  221,
+			HTMLURL: "https://github.com/mackerelio/mackerel-agent/pull/221",
+			User:    octokit.User{Login: "yukiyan"},
+		}}},
+		FromRevision: "v0.30.2",
+		ToRevision:   "v0.30.3",
+		ChangedAt:    time.Date(2016, 4, 27, 19, 5, 49, 0, time.UTC),
+		Owner:        "mackerelio",
+		Repo:         "mackerel-agent",
+	}}}
+	if err := writeSite(dir, chlog, "", "https://example.com/changes/"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"index.html", "v0-30-3.html", "feed.xml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s should be written: %s", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unreleased.html")); err == nil {
+		t.Errorf("empty unreleased section should not be published")
+	}
+
+	page, _ := ioutil.ReadFile(filepath.Join(dir, "v0-30-3.html"))
+	if strings.Contains(string(page), "<script>") {
+		t.Errorf("pull request titles should be escaped: %s", page)
+	}
+	feed, _ := ioutil.ReadFile(filepath.Join(dir, "feed.xml"))
+	if !strings.Contains(string(feed), `<link href="https://example.com/changes/v0-30-3.html"></link>`) {
+		t.Errorf("feed should link to the release page: %s", feed)
+	}
+}
+
+func TestSitePageContents(t *testing.T) {
+	pr := func(num int, title, login, author string) *PullRequest {
+		return &PullRequest{PullRequest: &octokit.PullRequest{Number: num, Title: title, User: octokit.User{Login: login}}, Author: author}
+	}
+	feature, fix := pr(1, "Add a feature", "alice", ""), pr(2, "Fix a crash", "", "Bob Smith")
+	sec := Section{
+		PullRequests:    []*PullRequest{feature, fix},
+		Categories:      []*Category{{Name: "Features", PullRequests: []*PullRequest{feature}}, {Name: "Fixes", PullRequests: []*PullRequest{fix}, Extras: []*Extra{{Title: "Drop Go 1.15", Author: "Jane Doe"}}}},
+		Highlights:      []*PullRequest{feature},
+		NewContributors: []*Contributor{{Login: "alice", PullRequest: 1}},
+		ToRevision:      "v0.2.0",
+		ChangedAt:       time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Owner:           "o",
+		Repo:            "r",
+		WebURL:          "https://ghe.example.com",
+	}
+	b, err := renderHTMLChangelog(Changelog{Sections: []Section{sec}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(b)
+	for _, expect := range []string{
+		"<h2>Highlights</h2>",
+		"<h2>Features</h2>",
+		"<h2>Fixes</h2>",
+		`<a href="https://ghe.example.com/o/r/pull/1">#1</a> (<a href="https://ghe.example.com/alice">alice</a>)`,
+		`<a href="https://ghe.example.com/o/r/pull/2">#2</a> (Bob Smith)`,
+		"<li>Drop Go 1.15 (Jane Doe)</li>",
+		`<a href="https://ghe.example.com/alice">alice</a> made their first contribution in <a href="https://ghe.example.com/o/r/pull/1">#1</a>`,
+	} {
+		if !strings.Contains(page, expect) {
+			t.Errorf("%q should be in the page: %s", expect, page)
+		}
+	}
+	if strings.Contains(page, "https://github.com") {
+		t.Errorf("links should be on the web URL of the section: %s", page)
+	}
+}
+
+func TestWriteSiteWithoutBaseURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghch-site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	chlog := Changelog{Sections: []Section{{ToRevision: "v0.1.0", Owner: "o", Repo: "r"}}}
+	if err := writeSite(dir, chlog, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "feed.xml")); err == nil {
+		t.Error("feed should not be written without --base-url")
+	}
+	index, _ := ioutil.ReadFile(filepath.Join(dir, "index.html"))
+	if strings.Contains(string(index), "feed.xml") {
+		t.Errorf("index should not link to the feed: %s", index)
+	}
+}
@@ -0,0 +1,52 @@
+package ghch
+
+import (
+	"github.com/pkg/errors"
+)
+
+// sizeRule names pull requests changing up to MaxLines lines, counting both
+// additions and deletions. A rule without MaxLines takes the rest.
+type sizeRule struct {
+	Name     string `yaml:"name"`
+	MaxLines int    `yaml:"max_lines"`
+}
+
+// defaultSizes are buckets commonly used by size labelers
+var defaultSizes = []*sizeRule{
+	{Name: "XS", MaxLines: 9},
+	{Name: "S", MaxLines: 29},
+	{Name: "M", MaxLines: 99},
+	{Name: "L", MaxLines: 499},
+	{Name: "XL"},
+}
+
+func validSizes(sizes []*sizeRule) error {
+	prev := -1
+	for i, s := range sizes {
+		if s.Name == "" {
+			return errors.Errorf("sizes[%d]: name is required", i)
+		}
+		if s.MaxLines == 0 && i != len(sizes)-1 {
+			return errors.Errorf("sizes[%d]: max_lines is required except for the last one", i)
+		}
+		if s.MaxLines != 0 && s.MaxLines <= prev {
+			return errors.Errorf("sizes[%d]: max_lines must be larger than the previous one", i)
+		}
+		prev = s.MaxLines
+	}
+	return nil
+}
+
+// sizeOf names the size of a change of lines, or returns "" when it is
+// larger than any of the sizes
+func sizeOf(sizes []*sizeRule, lines int) string {
+	if len(sizes) == 0 {
+		sizes = defaultSizes
+	}
+	for _, s := range sizes {
+		if s.MaxLines == 0 || lines <= s.MaxLines {
+			return s.Name
+		}
+	}
+	return ""
+}
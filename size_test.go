@@ -0,0 +1,41 @@
+package ghch
+
+import "testing"
+
+func TestSizeOf(t *testing.T) {
+	testCases := []struct {
+		sizes  []*sizeRule
+		lines  int
+		expect string
+	}{
+		{nil, 0, "XS"},
+		{nil, 9, "XS"},
+		{nil, 10, "S"},
+		{nil, 120, "L"},
+		{nil, 5000, "XL"},
+		{[]*sizeRule{{Name: "minor", MaxLines: 100}, {Name: "major"}}, 100, "minor"},
+		{[]*sizeRule{{Name: "minor", MaxLines: 100}, {Name: "major"}}, 101, "major"},
+		{[]*sizeRule{{Name: "small", MaxLines: 10}}, 11, ""},
+	}
+	for _, tc := range testCases {
+		if got := sizeOf(tc.sizes, tc.lines); got != tc.expect {
+			t.Errorf("%d lines: got %q, expect %q", tc.lines, got, tc.expect)
+		}
+	}
+}
+
+func TestValidSizes(t *testing.T) {
+	if err := validSizes(defaultSizes); err != nil {
+		t.Errorf("default sizes should be valid: %s", err)
+	}
+	invalids := [][]*sizeRule{
+		{{MaxLines: 10}},
+		{{Name: "any"}, {Name: "big", MaxLines: 100}},
+		{{Name: "small", MaxLines: 100}, {Name: "smaller", MaxLines: 10}},
+	}
+	for _, sizes := range invalids {
+		if err := validSizes(sizes); err == nil {
+			t.Errorf("%v should be invalid", sizes)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package ghch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stats are metrics of a section
+type Stats struct {
+	PullRequests int            `json:"pull_requests"`
+	ByCategory   map[string]int `json:"by_category,omitempty"`
+	Contributors int            `json:"contributors"`
+	// time from opening to merging pull requests
+	LeadTimeAverageHours float64 `json:"lead_time_average_hours"`
+	LeadTimeMedianHours  float64 `json:"lead_time_median_hours"`
+	// nil for the first release
+	DaysSincePrevious *float64 `json:"days_since_previous_release,omitempty"`
+}
+
+// noreplyEmailReg matches the commit emails GitHub gives its users, like
+// 12345+octocat@users.noreply.github.com
+var noreplyEmailReg = regexp.MustCompile(`(?i)^(?:[0-9]+\+)?([^@+]+)@users\.noreply\.github\.com$`)
+
+// commitLogin is the login of the commit author if known by the email, or
+// the author, which is a login when aliased and a name otherwise
+func commitLogin(c *Commit) string {
+	if m := noreplyEmailReg.FindStringSubmatch(c.Email); m != nil {
+		return m[1]
+	}
+	return c.Author
+}
+
+func computeStats(s Section, prevChangedAt time.Time) *Stats {
+	st := &Stats{PullRequests: len(s.PullRequests)}
+	contributors := make(map[string]bool)
+	var leadTimes []float64
+	for _, pr := range s.PullRequests {
+		if pr.Category != "" {
+			if st.ByCategory == nil {
+				st.ByCategory = make(map[string]int)
+			}
+			st.ByCategory[pr.Category]++
+		}
+		if pr.User.Login != "" {
+			contributors[strings.ToLower(pr.User.Login)] = true
+		} else if pr.Author != "" {
+			contributors[strings.ToLower(pr.Author)] = true
+		}
+		if pr.MergedAt != nil && !pr.CreatedAt.IsZero() {
+			leadTimes = append(leadTimes, pr.MergedAt.Sub(pr.CreatedAt).Hours())
+		}
+	}
+	for _, c := range s.DirectCommits {
+		contributors[strings.ToLower(commitLogin(c))] = true
+	}
+	st.Contributors = len(contributors)
+
+	if len(leadTimes) > 0 {
+		var sum float64
+		for _, l := range leadTimes {
+			sum += l
+		}
+		st.LeadTimeAverageHours = round(sum / float64(len(leadTimes)))
+		st.LeadTimeMedianHours = round(median(leadTimes))
+	}
+	if !prevChangedAt.IsZero() && !s.ChangedAt.IsZero() {
+		days := round(s.ChangedAt.Sub(prevChangedAt).Hours() / 24)
+		st.DaysSincePrevious = &days
+	}
+	return st
+}
+
+func median(vals []float64) float64 {
+	sorted := append([]float64(nil), vals...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func round(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
+
+// Summary is the line rendered under the heading of markdown sections
+func (st *Stats) Summary() string {
+	parts := []string{fmt.Sprintf("%d pull requests by %d contributors", st.PullRequests, st.Contributors)}
+	if st.PullRequests > 0 {
+		parts = append(parts, fmt.Sprintf("median lead time %.1f days", st.LeadTimeMedianHours/24))
+	}
+	if st.DaysSincePrevious != nil {
+		parts = append(parts, fmt.Sprintf("%.0f days since the previous release", *st.DaysSincePrevious))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (cli *CLI) runStats(argv []string) int {
+	opts := &ghOpts{}
+	p, _, err := parseCommandArgs("stats", opts, argv)
+	if err != nil {
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+	opts.Stats = true
+
+	gh, err := opts.newGhch()
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	var chlog Changelog
+	if opts.All {
+		chlog = gh.getChangelog(opts.NextVersion)
+	} else {
+		chlog = Changelog{Sections: []Section{gh.getCurrentSection(opts.From, opts.To, opts.NextVersion)}}
+	}
+	rows := statsRows(chlog)
+	switch opts.Format {
+	case "csv":
+		err = writeStatsCSV(cli.OutStream, rows)
+	case "json":
+		var jsn []byte
+		jsn, err = json.MarshalIndent(rows, "", "  ")
+		fmt.Fprintln(cli.OutStream, string(jsn))
+	default:
+		log.Printf("unsupported format %q for stats: must be csv or json", opts.Format)
+		return exitCodeParseFlagError
+	}
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	return gh.exitCode(chlog.isEmpty(), opts.ExitCode || opts.Quiet)
+}
+
+// statsRow is a row of the stats report per release
+type statsRow struct {
+	Version      string    `json:"version"`
+	FromRevision string    `json:"from_revision"`
+	ChangedAt    time.Time `json:"changed_at"`
+	*Stats
+	// merged pull requests per week since the previous release
+	PullRequestsPerWeek *float64 `json:"pull_requests_per_week,omitempty"`
+}
+
+func statsRows(chlog Changelog) []statsRow {
+	var rows []statsRow
+	for _, s := range chlog.Sections {
+		st := s.Stats
+		if st == nil {
+			st = computeStats(s, time.Time{})
+		}
+		row := statsRow{Version: s.ToRevision, FromRevision: s.FromRevision, ChangedAt: s.ChangedAt, Stats: st}
+		if d := st.DaysSincePrevious; d != nil && *d > 0 {
+			perWeek := round(float64(st.PullRequests) / *d * 7)
+			row.PullRequestsPerWeek = &perWeek
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func writeStatsCSV(w io.Writer, rows []statsRow) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"version", "from_revision", "changed_at", "pull_requests", "contributors",
+		"lead_time_average_hours", "lead_time_median_hours", "days_since_previous_release", "pull_requests_per_week",
+	})
+	optFloat := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*f, 'f', -1, 64)
+	}
+	for _, r := range rows {
+		cw.Write([]string{
+			r.Version,
+			r.FromRevision,
+			r.ChangedAt.Format(time.RFC3339),
+			strconv.Itoa(r.PullRequests),
+			strconv.Itoa(r.Contributors),
+			strconv.FormatFloat(r.LeadTimeAverageHours, 'f', -1, 64),
+			strconv.FormatFloat(r.LeadTimeMedianHours, 'f', -1, 64),
+			optFloat(r.DaysSincePrevious),
+			optFloat(r.PullRequestsPerWeek),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
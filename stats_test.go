@@ -0,0 +1,91 @@
+package ghch
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestComputeStats(t *testing.T) {
+	base := time.Date(2016, 4, 20, 0, 0, 0, 0, time.UTC)
+	pr := func(login, category string, leadHours int) *PullRequest {
+		merged := base.Add(time.Duration(leadHours) * time.Hour)
+		return &PullRequest{
+			PullRequest: &octokit.PullRequest{User: octokit.User{Login: login}, CreatedAt: base, MergedAt: &merged},
+			Category:    category,
+		}
+	}
+	s := Section{
+		PullRequests: []*PullRequest{
+			pr("Songmu", "Features", 10),
+			pr("Songmu", "Fixes", 2),
+			pr("yukiyan", "Fixes", 48),
+			pr("itchyny", "Fixes", 4),
+		},
+		DirectCommits: []*Commit{{Author: "stefafafan"}},
+		ChangedAt:     base.Add(7 * 24 * time.Hour),
+	}
+	st := computeStats(s, base)
+
+	days := 7.0
+	expect := &Stats{
+		PullRequests:         4,
+		ByCategory:           map[string]int{"Features": 1, "Fixes": 3},
+		Contributors:         4,
+		LeadTimeAverageHours: 16,
+		LeadTimeMedianHours:  7,
+		DaysSincePrevious:    &days,
+	}
+	if !reflect.DeepEqual(st, expect) {
+		t.Errorf("got %+v, expect %+v", st, expect)
+	}
+	if got := st.Summary(); got != "4 pull requests by 4 contributors, median lead time 0.3 days, 7 days since the previous release" {
+		t.Errorf("unexpected summary: %s", got)
+	}
+
+	// the same person by login, by the noreply email of a commit, or by the name of an offline author
+	s = Section{
+		PullRequests: []*PullRequest{
+			pr("Songmu", "", 1),
+			{PullRequest: &octokit.PullRequest{}, Author: "Erin Smith"},
+		},
+		DirectCommits: []*Commit{
+			{Author: "Songmu Ai", Email: "1234+songmu@users.noreply.github.com"},
+			{Author: "erin smith", Email: "erin@example.com"},
+		},
+	}
+	if st := computeStats(s, time.Time{}); st.Contributors != 2 {
+		t.Errorf("got %d contributors, expect 2", st.Contributors)
+	}
+
+	if st := computeStats(Section{}, time.Time{}); st.DaysSincePrevious != nil || st.PullRequests != 0 {
+		t.Errorf("unexpected stats of empty section: %+v", st)
+	}
+}
+
+func TestWriteStatsCSV(t *testing.T) {
+	days := 14.0
+	chlog := Changelog{Sections: []Section{{
+		ToRevision:   "v0.30.3",
+		FromRevision: "v0.30.2",
+		ChangedAt:    time.Date(2016, 4, 27, 0, 0, 0, 0, time.UTC),
+		Stats:        &Stats{PullRequests: 6, Contributors: 4, LeadTimeAverageHours: 40.2, LeadTimeMedianHours: 31.5, DaysSincePrevious: &days},
+	}, {
+		ToRevision: "v0.30.2",
+		ChangedAt:  time.Date(2016, 4, 13, 0, 0, 0, 0, time.UTC),
+	}}}
+	var b bytes.Buffer
+	if err := writeStatsCSV(&b, statsRows(chlog)); err != nil {
+		t.Fatal(err)
+	}
+	expect := `version,from_revision,changed_at,pull_requests,contributors,lead_time_average_hours,lead_time_median_hours,days_since_previous_release,pull_requests_per_week
+v0.30.3,v0.30.2,2016-04-27T00:00:00Z,6,4,40.2,31.5,14,3
+v0.30.2,,2016-04-13T00:00:00Z,0,0,0,0,,
+`
+	if b.String() != expect {
+		t.Errorf("got\n%s\nexpect\n%s", b.String(), expect)
+	}
+}
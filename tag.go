@@ -0,0 +1,138 @@
+package ghch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type tagOpts struct {
+	ghOpts
+	Bump string `long:"bump" description:"part of the latest version to increment: major, minor or patch"`
+	Push bool   `long:"push" description:"push the created tag to the remote"`
+}
+
+func (cli *CLI) runTag(argv []string) int {
+	opts := &tagOpts{}
+	p, _, err := parseCommandArgs("tag", opts, argv)
+	if err != nil {
+		return cli.parseError(p, err)
+	}
+	cli.setQuiet(opts.Quiet)
+
+	opts.editsOutput = true
+	gh, err := opts.newGhch()
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	rdr, err := opts.newRenderer(gh.config)
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	tag := opts.NextVersion
+	if tag == "" {
+		if opts.Bump == "" {
+			log.Print("no tag to create. specify --bump or --next-version")
+			return exitCodeParseFlagError
+		}
+		if gh.scheme != nil {
+			log.Print("--bump works only with semver tags. specify --next-version with --version-scheme")
+			return exitCodeParseFlagError
+		}
+		if tag, err = bumpVersion(gh.getLatestSemverTag(), opts.Bump); err != nil {
+			log.Print(err)
+			return exitCodeParseFlagError
+		}
+	}
+	if _, ok := gh.tagRefs()[tag]; ok {
+		log.Printf("tag %s already exists", tag)
+		return exitCodeErr
+	}
+	msg, err := rdr.section(gh.getCurrentSection(opts.From, "", tag))
+	if err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	if opts.Edit {
+		if msg, err = editText(msg); err != nil {
+			log.Print(err)
+			return exitCodeErr
+		}
+	}
+
+	if opts.DryRun {
+		push := ""
+		if opts.Push {
+			push = " and push it to " + gh.getRemote()
+		}
+		fmt.Fprintf(cli.OutStream, "would create tag %s on %s%s\n\n%s\n", tag, gh.head(), push, msg)
+		return gh.exitCode(false, false)
+	}
+	if err := gh.createTag(tag, msg); err != nil {
+		log.Print(err)
+		return exitCodeErr
+	}
+	if opts.Push {
+		if _, err := gh.cmd("push", gh.getRemote(), "refs/tags/"+tag); err != nil {
+			log.Print(errors.Wrapf(err, "failed to push tag %s. `git push` failed", tag))
+			return exitCodeErr
+		}
+	}
+	fmt.Fprintln(cli.OutStream, tag)
+	return gh.exitCode(false, false)
+}
+
+// createTag creates an annotated tag on the head with msg as its message
+func (gh *ghch) createTag(tag, msg string) error {
+	f, err := ioutil.TempFile("", "ghch-tag-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create tag message")
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(msg)
+	f.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to create tag message")
+	}
+	// verbatim keeps markdown headings, which git regards as comments
+	if _, err := gh.cmd("tag", "-a", "--cleanup=verbatim", "-F", f.Name(), tag, gh.head()); err != nil {
+		return errors.Wrapf(err, "failed to create tag %s. `git tag` failed", tag)
+	}
+	return nil
+}
+
+// bumpVersion increments part of ver keeping its "v" prefix. An empty ver
+// is regarded as v0.0.0.
+func bumpVersion(ver, part string) (string, error) {
+	prefix := "v"
+	if ver != "" && !strings.HasPrefix(ver, "v") {
+		prefix = ""
+	}
+	nums := []int{0, 0, 0}
+	if ver != "" {
+		if !verReg.MatchString(ver) {
+			return "", errors.Errorf("failed to bump %s: not a version", ver)
+		}
+		for i, f := range strings.Split(strings.TrimPrefix(ver, "v"), ".") {
+			nums[i], _ = strconv.Atoi(f)
+		}
+	}
+	switch part {
+	case "major":
+		nums = []int{nums[0] + 1, 0, 0}
+	case "minor":
+		nums = []int{nums[0], nums[1] + 1, 0}
+	case "patch":
+		nums[2]++
+	default:
+		return "", errors.Errorf("unknown --bump %q: must be major, minor or patch", part)
+	}
+	return fmt.Sprintf("%s%d.%d.%d", prefix, nums[0], nums[1], nums[2]), nil
+}
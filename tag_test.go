@@ -0,0 +1,27 @@
+package ghch
+
+import "testing"
+
+func TestBumpVersion(t *testing.T) {
+	testCases := []struct {
+		ver, part, expect string
+	}{
+		{"v0.30.2", "patch", "v0.30.3"},
+		{"v0.30.2", "minor", "v0.31.0"},
+		{"v0.30.2", "major", "v1.0.0"},
+		{"1.2", "patch", "1.2.1"},
+		{"", "minor", "v0.1.0"},
+	}
+	for _, tc := range testCases {
+		got, err := bumpVersion(tc.ver, tc.part)
+		if err != nil {
+			t.Errorf("%s %s: %s", tc.ver, tc.part, err)
+		}
+		if got != tc.expect {
+			t.Errorf("%s %s: got %s, expect %s", tc.ver, tc.part, got, tc.expect)
+		}
+	}
+	if _, err := bumpVersion("v1.0.0", "build"); err == nil {
+		t.Error("unknown part should be an error")
+	}
+}
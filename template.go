@@ -0,0 +1,422 @@
+package ghch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// default markdown templates. Each of them can be overridden by a file with
+// the same name plus ".tmpl" in the template directory.
+var tmplStr = `{{define "header"}}{{end}}
+{{- define "group"}}## {{.Name}}{{end}}
+{{- define "week"}}## {{.Week}} ({{.Start.Format "Jan 2"}} - {{.End.Format "Jan 2"}}){{end}}
+{{- define "section"}}{{$ret := . -}}
+## [{{.ToRevision}}]({{releaseURL . .ToRevision}}) ({{.ChangedAt.Format "2006-01-02"}})
+{{- if .Degraded}}
+
+_Built from git history only: titles and authors are inferred from merge and squash commits._
+{{- end}}
+{{- if .Incomplete}}
+
+_Incomplete: some pull requests could not be fetched._
+{{- end}}
+{{- with .Stats}}
+
+_{{.Summary}}_
+{{- end}}
+{{- if .Highlights}}
+
+### Highlights
+{{range .Highlights}}
+{{template "item" item $ret .}}
+{{- end}}
+{{- end}}
+{{- if .Categories}}
+{{- range .Categories}}
+{{- if .Collapsed}}
+
+<details>
+<summary>{{.Name}} ({{len .PullRequests}})</summary>
+{{range .PullRequests}}
+{{template "item" item $ret .}}
+{{- end}}
+{{- range .Extras}}
+{{template "extra" extra $ret .}}
+{{- end}}
+
+</details>
+{{- else}}
+
+### {{.Name}}
+{{range .PullRequests}}
+{{template "item" item $ret .}}
+{{- end}}
+{{- range .Extras}}
+{{template "extra" extra $ret .}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- else}}
+{{range .PullRequests}}
+{{template "item" item $ret .}}
+{{- end}}
+{{- range .Extras}}
+{{template "extra" extra $ret .}}
+{{- end}}
+{{- end}}
+{{- if .DirectCommits}}
+
+### Direct commits
+{{range .DirectCommits}}
+{{template "commit" commit $ret .}}
+{{- end}}
+{{- end}}
+{{- if .NewContributors}}
+
+### New Contributors
+{{range .NewContributors}}
+* [{{.Login}}]({{userURL $ret .Login}}) made their first contribution in [#{{.PullRequest}}]({{prURL $ret .PullRequest}})
+{{- end}}
+{{- end}}
+{{- end}}
+{{- define "item" -}}
+* {{with .Component}}**{{.}}**: {{end}}{{.Title}} [#{{.Number}}]({{prURL .Section .Number}}) ({{with .User.Login}}{{template "user" user $.Section .}}{{else}}{{.Author}}{{end}})
+{{- with .Size}} [{{.}}]{{end}}
+{{- template "backport" .}}
+{{- with .RevertedBy}} (reverted by [#{{.}}]({{prURL $.Section .}})){{end}}
+{{- end}}
+{{- define "extra" -}}
+* {{if .URL}}[{{.Title}}]({{.URL}}){{else}}{{.Title}}{{end}}
+{{- with .Author}} ({{template "user" user $.Section .}}){{end}}
+{{- end}}
+{{- define "user"}}{{with .URL}}[{{$.Name}}]({{.}}){{else}}{{.Name}}{{end}}{{end}}
+{{- define "commit" -}}
+* {{.Subject}} [{{.SHA}}]({{commitURL .Section .SHA}}) ({{.Author}})
+{{- template "backport" .}}
+{{- with .RevertedBy}} (reverted by {{.}}){{end}}
+{{- end}}
+{{- define "backport"}}
+{{- with .Backport}} (backport of {{if .PullRequest}}[#{{.PullRequest}}]({{prURL $.Section .PullRequest}}){{else}}{{.Commit}}{{end}}
+{{- with .Release}} from [{{.}}]({{releaseURL $.Section .}}){{end}}){{end}}
+{{- end}}`
+
+// prItem is passed to the "item" template
+type prItem struct {
+	*PullRequest
+	Section Section
+}
+
+// commitItem is passed to the "commit" template
+type commitItem struct {
+	*Commit
+	Section Section
+}
+
+// userItem is passed to the "user" template, linked unless URL is empty
+type userItem struct {
+	Name string
+	URL  string
+}
+
+var tmplFuncs = template.FuncMap{
+	"item": func(s Section, pr *PullRequest) prItem {
+		return prItem{PullRequest: pr, Section: s}
+	},
+	"commit": func(s Section, c *Commit) commitItem {
+		return commitItem{Commit: c, Section: s}
+	},
+	"extra": func(s Section, e *Extra) extraItem {
+		return extraItem{Extra: e, Section: s}
+	},
+	"user": func(s Section, name string) userItem {
+		return userItem{Name: name, URL: userURL(s, name)}
+	},
+	"escapeHTML":     escapeTitleHTML,
+	"escapeMarkdown": escapeTitleMarkdown,
+	"prURL":          prURL,
+	"userURL":        userURL,
+	"commitURL":      commitURL,
+	"releaseURL":     releaseURL,
+	"compareURL":     compareURL,
+}
+
+// repoURL is the page of the repository of the section on the configured
+// web URL, such as a GitHub Enterprise Server, which custom templates should
+// link to through the helpers below instead of https://github.com
+func repoURL(s Section) string {
+	return webRootOf(s) + "/" + s.Owner + "/" + s.Repo
+}
+
+func webRootOf(s Section) string {
+	if s.WebURL == "" {
+		return defaultWebURL
+	}
+	return strings.TrimSuffix(s.WebURL, "/")
+}
+
+func prURL(s Section, num int) string {
+	return fmt.Sprintf("%s/pull/%d", repoURL(s), num)
+}
+
+// userURL is empty for a name which is not a login, such as a git author
+func userURL(s Section, login string) string {
+	if login == "" || strings.ContainsAny(login, " \t") {
+		return ""
+	}
+	return webRootOf(s) + "/" + login
+}
+
+func commitURL(s Section, sha string) string {
+	return repoURL(s) + "/commit/" + sha
+}
+
+func releaseURL(s Section, tag string) string {
+	return repoURL(s) + "/releases/tag/" + tag
+}
+
+// compareURL shows the changes of the section, or its history when it has
+// no previous version
+func compareURL(s Section) string {
+	to := s.ToRevision
+	if to == "" {
+		to = "HEAD"
+	}
+	if s.FromRevision == "" {
+		return repoURL(s) + "/commits/" + to
+	}
+	return repoURL(s) + "/compare/" + s.FromRevision + "..." + to
+}
+
+var mdTmpl *template.Template
+
+func init() {
+	var err error
+	mdTmpl, err = template.New("md-changelog").Funcs(tmplFuncs).Parse(tmplStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadTemplates returns the default templates overridden by *.tmpl files in
+// dir. Files are registered by their base name without the extension, so
+// partials besides header, section and item can be shared via {{template}}.
+func loadTemplates(dir string) (*template.Template, error) {
+	if dir == "" {
+		return mdTmpl, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load templates")
+	}
+	if len(files) < 1 {
+		return nil, errors.Errorf("no *.tmpl files found in %s", dir)
+	}
+	tmpl, err := mdTmpl.Clone()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load templates")
+	}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load templates")
+		}
+		name := strings.TrimSuffix(filepath.Base(f), ".tmpl")
+		if _, err := tmpl.New(name).Parse(string(b)); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse template %s", f)
+		}
+	}
+	return tmpl, nil
+}
+
+// renderer renders sections and changelogs as markdown
+type renderer struct {
+	tmpl        *template.Template
+	frontMatter string
+
+	titleEscape    string
+	escapeMentions bool
+	escapeRefs     bool
+	// group sections of a whole changelog by year or month
+	groupBy string
+	vars    map[string]string
+	// collapse categories of more pull requests than this in <details>
+	collapse int
+	// prefix a whole changelog with the index of its versions
+	toc bool
+	// normalize titles as the config says
+	titles *titleRules
+}
+
+// collapseCategories marks copies of the categories having more pull
+// requests than max to be collapsed
+func collapseCategories(cats []*Category, max int) []*Category {
+	ret := make([]*Category, 0, len(cats))
+	for _, c := range cats {
+		cc := *c
+		cc.Collapsed = len(c.PullRequests) > max
+		ret = append(ret, &cc)
+	}
+	return ret
+}
+
+// parseVars parses key=value pairs of --var
+func parseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) < 2 || kv[0] == "" {
+			return nil, errors.Errorf("invalid --var %q: must be key=value", p)
+		}
+		vars[kv[0]] = kv[1]
+	}
+	return vars, nil
+}
+
+func (r *renderer) escapeTitle(str string) string {
+	if r.titles != nil {
+		str = r.titles.normalize(str)
+	}
+	switch r.titleEscape {
+	case escapeHTML:
+		str = escapeTitleHTML(str)
+	case escapeMarkdown:
+		str = escapeTitleMarkdown(str)
+	}
+	if r.escapeMentions {
+		str = escapeMentions(str)
+	}
+	if r.escapeRefs {
+		str = escapeIssueRefs(str)
+	}
+	return str
+}
+
+func (r *renderer) section(rs Section) (string, error) {
+	if rs.WebURL == "" {
+		rs.WebURL = defaultWebURL
+	}
+	if (r.titleEscape != "" && r.titleEscape != escapeNone) || r.escapeMentions || r.escapeRefs || r.titles != nil {
+		rs = mapTitles(rs, r.escapeTitle)
+	}
+	if rs.Vars == nil {
+		rs.Vars = r.vars
+	}
+	if r.collapse > 0 {
+		rs.Categories = collapseCategories(rs.Categories, r.collapse)
+	}
+	var b bytes.Buffer
+	if r.frontMatter == frontMatterSection {
+		b.WriteString(sectionFrontMatter(rs))
+	}
+	err := r.tmpl.ExecuteTemplate(&b, "section", rs)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (r *renderer) changelog(chlog Changelog) (string, error) {
+	var b bytes.Buffer
+	err := r.writeChangelog(&b, &chlog, func(fn func(Section) error) error {
+		for _, s := range chlog.Sections {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if r.toc {
+		return insertTOC(b.String(), r.groupBy != ""), nil
+	}
+	return b.String(), nil
+}
+
+// streamChangelog writes each section produced by each as soon as it is
+// rendered. The header template is executed before the first section, so it
+// receives a Changelog without sections.
+func (r *renderer) streamChangelog(w io.Writer, each func(func(Section) error) error) error {
+	return r.writeChangelog(w, nil, each)
+}
+
+// headerNeedsSections reports whether the header template refers to
+// .Sections, which streaming can't give
+func (r *renderer) headerNeedsSections() bool {
+	t := r.tmpl.Lookup("header")
+	return t != nil && t.Tree != nil && strings.Contains(t.Tree.Root.String(), ".Sections")
+}
+
+// writeChangelog writes the sections produced by each, giving the header
+// template whole, or a Changelog without sections when streaming
+func (r *renderer) writeChangelog(w io.Writer, whole *Changelog, each func(func(Section) error) error) error {
+	started, sep := false, false
+	start := func(first *Section) error {
+		started = true
+		head := Changelog{}
+		if whole != nil {
+			head = *whole
+		} else if first != nil {
+			head.Sections = []Section{*first}
+		}
+		if r.frontMatter == frontMatterDocument {
+			io.WriteString(w, changelogFrontMatter(head))
+		}
+		if whole == nil {
+			head.Sections = nil
+		}
+		if head.Vars == nil {
+			head.Vars = r.vars
+		}
+		var b bytes.Buffer
+		if err := r.tmpl.ExecuteTemplate(&b, "header", head); err != nil {
+			return err
+		}
+		if header := strings.TrimSpace(b.String()); header != "" {
+			io.WriteString(w, header)
+			sep = true
+		}
+		return nil
+	}
+	var group *Group
+	err := each(func(s Section) error {
+		if !started {
+			if err := start(&s); err != nil {
+				return err
+			}
+		}
+		str, err := r.section(s)
+		if err != nil {
+			return err
+		}
+		if r.groupBy != "" {
+			var heading string
+			if group, heading, err = r.groupHeading(group, s.ChangedAt); err != nil {
+				return err
+			}
+			str = demoteHeadings(str)
+			if heading != "" {
+				str = heading + "\n\n" + str
+			}
+		}
+		if sep {
+			str = "\n\n" + str
+		}
+		sep = true
+		_, err = io.WriteString(w, str)
+		return err
+	})
+	if err != nil || started {
+		return err
+	}
+	return start(nil)
+}
@@ -0,0 +1,195 @@
+package ghch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghch-tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"header.tmpl":  "# Changes",
+		"section.tmpl": `{{$ret := . -}}### {{.ToRevision}}{{range .PullRequests}}` + "\n" + `{{template "item" item $ret .}}{{end}}`,
+		"item.tmpl":    `- {{template "link" .}}`,
+		"link.tmpl":    `{{.Title}} (#{{.Number}} in {{.Section.Repo}})`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmpl, err := loadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chlog := Changelog{Sections: []Section{{
+		PullRequests: []*PullRequest{{PullRequest: &octokit.PullRequest{Title: "Fix typo", Number: 221}}},
+		ToRevision:   "v0.30.3",
+		ChangedAt:    time.Now(),
+		Repo:         "mackerel-agent",
+	}}}
+	got, err := (&renderer{tmpl: tmpl}).changelog(chlog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "# Changes\n\n### v0.30.3\n- Fix typo (#221 in mackerel-agent)"
+	if got != expect {
+		t.Errorf("got %q, expect %q", got, expect)
+	}
+
+	if _, err := loadTemplates(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("loading templates from missing directory should fail")
+	}
+}
+
+func TestTemplateVars(t *testing.T) {
+	vars, err := parseVars([]string{"codename=Hydrogen", "url=https://example.com/?a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["url"] != "https://example.com/?a=b" {
+		t.Errorf("values should be split at the first =: %q", vars["url"])
+	}
+	if _, err := parseVars([]string{"codename"}); err == nil {
+		t.Error("a var without = should be an error")
+	}
+
+	tmpl, err := mdTmpl.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.Must(tmpl.New("header").Parse(`# {{.Vars.codename}}`))
+	template.Must(tmpl.New("section").Parse(`{{.ToRevision}} "{{.Vars.codename}}"{{range .PullRequests}} {{template "item" item $ . }}{{end}}`))
+	template.Must(tmpl.New("item").Parse(`{{.Section.Vars.url}}`))
+	r := &renderer{tmpl: tmpl, vars: vars}
+	str, err := r.changelog(Changelog{Sections: []Section{{
+		ToRevision:   "v1.0.0",
+		PullRequests: []*PullRequest{{PullRequest: &octokit.PullRequest{Number: 1}}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "# Hydrogen\n\nv1.0.0 \"Hydrogen\" https://example.com/?a=b"; str != expect {
+		t.Errorf("got %q, expect %q", str, expect)
+	}
+}
+
+func TestCollapseCategories(t *testing.T) {
+	newPR := func(num int) *PullRequest {
+		return &PullRequest{PullRequest: &octokit.PullRequest{Number: num, Title: "Bump", User: octokit.User{Login: "dependabot[bot]"}}}
+	}
+	s := Section{
+		ToRevision: "v1.0.0",
+		Owner:      "o",
+		Repo:       "r",
+		Categories: []*Category{
+			{Name: "Features", PullRequests: []*PullRequest{newPR(1)}},
+			{Name: "Dependencies", PullRequests: []*PullRequest{newPR(2), newPR(3)}},
+		},
+	}
+	r := &renderer{tmpl: mdTmpl, collapse: 1}
+	str, err := r.section(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `## [v1.0.0](https://github.com/o/r/releases/tag/v1.0.0) (0001-01-01)
+
+### Features
+
+* Bump [#1](https://github.com/o/r/pull/1) ([dependabot[bot]](https://github.com/dependabot[bot]))
+
+<details>
+<summary>Dependencies (2)</summary>
+
+* Bump [#2](https://github.com/o/r/pull/2) ([dependabot[bot]](https://github.com/dependabot[bot]))
+* Bump [#3](https://github.com/o/r/pull/3) ([dependabot[bot]](https://github.com/dependabot[bot]))
+
+</details>`
+	if str != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", str, expect)
+	}
+	if s.Categories[1].Collapsed {
+		t.Error("categories of the section should be left as they are")
+	}
+}
+
+func TestURLHelpers(t *testing.T) {
+	tmpl := template.Must(template.New("links").Funcs(tmplFuncs).Parse(
+		`{{prURL . 12}} {{userURL . "Songmu"}} {{commitURL . "abc1234"}} {{releaseURL . .ToRevision}} {{compareURL .}}`))
+	testCases := []struct {
+		section Section
+		expect  string
+	}{
+		{
+			Section{Owner: "Songmu", Repo: "ghch", FromRevision: "v0.1.0", ToRevision: "v0.2.0"},
+			"https://github.com/Songmu/ghch/pull/12 https://github.com/Songmu https://github.com/Songmu/ghch/commit/abc1234 " +
+				"https://github.com/Songmu/ghch/releases/tag/v0.2.0 https://github.com/Songmu/ghch/compare/v0.1.0...v0.2.0",
+		},
+		{
+			// unreleased on GitHub Enterprise Server
+			Section{Owner: "o", Repo: "r", FromRevision: "v0.2.0", WebURL: "https://ghe.example.com/"},
+			"https://ghe.example.com/o/r/pull/12 https://ghe.example.com/Songmu https://ghe.example.com/o/r/commit/abc1234 " +
+				"https://ghe.example.com/o/r/releases/tag/ https://ghe.example.com/o/r/compare/v0.2.0...HEAD",
+		},
+		{
+			// the first version
+			Section{Owner: "o", Repo: "r", ToRevision: "v0.1.0"},
+			"https://github.com/o/r/pull/12 https://github.com/Songmu https://github.com/o/r/commit/abc1234 " +
+				"https://github.com/o/r/releases/tag/v0.1.0 https://github.com/o/r/commits/v0.1.0",
+		},
+	}
+	for _, tc := range testCases {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, tc.section); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tc.expect {
+			t.Errorf("got:\n%s\nexpect:\n%s", got, tc.expect)
+		}
+	}
+	for _, name := range []string{"", "Erin Smith"} {
+		if u := userURL(Section{}, name); u != "" {
+			t.Errorf("%q is not a login and should not be linked: %s", name, u)
+		}
+	}
+}
+
+func TestHeaderOfChangelog(t *testing.T) {
+	tmpl, err := mdTmpl.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.Must(tmpl.New("header").Parse(`# {{len .Sections}} versions`))
+	r := &renderer{tmpl: tmpl}
+	if !r.headerNeedsSections() {
+		t.Error("the header refers to .Sections")
+	}
+	if (&renderer{tmpl: mdTmpl}).headerNeedsSections() {
+		t.Error("the default header doesn't refer to .Sections")
+	}
+
+	chlog := Changelog{Sections: []Section{
+		{ToRevision: "v0.2.0", Owner: "Songmu", Repo: "ghch"},
+		{ToRevision: "v0.1.0", Owner: "Songmu", Repo: "ghch"},
+	}}
+	got, err := r.changelog(chlog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "# 2 versions\n") {
+		t.Errorf("the header should receive the whole changelog: %s", got)
+	}
+}
@@ -0,0 +1,93 @@
+package ghch
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// titleRules normalize titles of pull requests, commit subjects and extras
+// when they are rendered, so that titles written in various styles look
+// uniform in the changelog
+type titleRules struct {
+	// pattern removed from the start, such as conventional commit types
+	StripPrefix         string `yaml:"strip_prefix"`
+	CollapseWhitespace  bool   `yaml:"collapse_whitespace"`
+	StripTrailingPeriod bool   `yaml:"strip_trailing_period"`
+	Capitalize          bool   `yaml:"capitalize"`
+	// lowercase capitalized words after the first one, implying capitalize
+	SentenceCase bool `yaml:"sentence_case"`
+
+	prefixReg *regexp.Regexp
+}
+
+func (tr *titleRules) compile() error {
+	if tr.StripPrefix == "" {
+		return nil
+	}
+	reg, err := regexp.Compile(tr.StripPrefix)
+	if err != nil {
+		return errors.Wrap(err, "titles: invalid strip_prefix pattern")
+	}
+	tr.prefixReg = reg
+	return nil
+}
+
+func (tr *titleRules) normalize(str string) string {
+	if tr.CollapseWhitespace {
+		str = strings.Join(strings.Fields(str), " ")
+	}
+	if tr.prefixReg != nil {
+		if loc := tr.prefixReg.FindStringIndex(str); loc != nil && loc[0] == 0 && loc[1] < len(str) {
+			str = strings.TrimLeft(str[loc[1]:], " \t")
+		}
+	}
+	if tr.StripTrailingPeriod && strings.HasSuffix(str, ".") && !strings.HasSuffix(str, "..") {
+		str = strings.TrimRight(strings.TrimSuffix(str, "."), " ")
+	}
+	words := strings.Split(str, " ")
+	if tr.SentenceCase {
+		for i := 1; i < len(words); i++ {
+			if isCapitalized(words[i]) {
+				words[i] = strings.ToLower(words[i])
+			}
+		}
+	}
+	if words[0] == "" {
+		// nothing to capitalize in an empty title or one led by a space
+		return strings.Join(words, " ")
+	}
+	if (tr.Capitalize || tr.SentenceCase) && !looksLikeCode(words[0]) {
+		r, size := utf8.DecodeRuneInString(words[0])
+		words[0] = string(unicode.ToUpper(r)) + words[0][size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// isCapitalized reports whether the word is an uppercase letter followed by
+// lowercase ones only, leaving acronyms and names like GitHub as they are
+func isCapitalized(word string) bool {
+	for i, r := range word {
+		if i == 0 && !unicode.IsUpper(r) || i > 0 && !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return len(word) > 1
+}
+
+// looksLikeCode reports whether the word is an identifier or a path such as
+// go.mod, gRPC or `--all`, which are not capitalized
+func looksLikeCode(word string) bool {
+	if strings.ContainsAny(word, "._/`()") {
+		return true
+	}
+	for i, r := range word {
+		if i > 0 && unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
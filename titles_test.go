@@ -0,0 +1,62 @@
+package ghch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestTitleRulesNormalize(t *testing.T) {
+	tr := &titleRules{
+		StripPrefix:         `^(feat|fix|chore)(\([^)]*\))?!?:`,
+		CollapseWhitespace:  true,
+		StripTrailingPeriod: true,
+		SentenceCase:        true,
+	}
+	if err := tr.compile(); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		input, expect string
+	}{
+		{"add  --all option.", "Add --all option"},
+		{"feat(cli): Support Multiple Formats", "Support multiple formats"},
+		{"Fix GitHub API Pagination", "Fix GitHub API pagination"},
+		{"go.mod: update deps", "go.mod: update deps"},
+		{"gRPC client", "gRPC client"},
+		{"Wait for it...", "Wait for it..."},
+		{"fix:", "Fix:"},
+		{"über", "Über"},
+		{".", ""},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		if got := tr.normalize(tc.input); got != tc.expect {
+			t.Errorf("normalize(%q) = %q, want %q", tc.input, got, tc.expect)
+		}
+	}
+
+	capitalize := &titleRules{Capitalize: true}
+	if got := capitalize.normalize("add Support"); got != "Add Support" {
+		t.Errorf("capitalize should leave the rest: %q", got)
+	}
+	if got := capitalize.normalize("  "); got != "  " {
+		t.Errorf("capitalize should leave a blank title: %q", got)
+	}
+}
+
+func TestRenderNormalizedTitles(t *testing.T) {
+	pr := &PullRequest{PullRequest: &octokit.PullRequest{Number: 1, Title: "fix typo.", User: octokit.User{Login: "Songmu"}}}
+	s := Section{ToRevision: "v1.0.0", Owner: "o", Repo: "r", PullRequests: []*PullRequest{pr}}
+	str, err := (&renderer{tmpl: mdTmpl, titles: &titleRules{Capitalize: true, StripTrailingPeriod: true}}).section(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(str, "* Fix typo [#1]") {
+		t.Errorf("got:\n%s", str)
+	}
+	if pr.Title != "fix typo." {
+		t.Errorf("the original title should be intact: %q", pr.Title)
+	}
+}
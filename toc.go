@@ -0,0 +1,94 @@
+package ghch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// githubSlug returns the anchor GitHub gives to a heading of the text, which
+// is lowercased with spaces turned into hyphens and punctuation dropped
+func githubSlug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+var (
+	inlineLinkReg = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	emphasisReg   = regexp.MustCompile("[*`]")
+)
+
+// headingText strips markdown of a heading to the text GitHub renders
+func headingText(md string) string {
+	return strings.TrimSpace(emphasisReg.ReplaceAllString(inlineLinkReg.ReplaceAllString(md, "$1"), ""))
+}
+
+// tocEntry is a heading listed in the table of contents
+type tocEntry struct {
+	level  int
+	text   string
+	anchor string
+}
+
+// tocEntries lists headings of the levels up to maxLevel with their anchors.
+// Anchors of all headings are taken into account since GitHub suffixes the
+// duplicated ones with their counts.
+func tocEntries(doc string, maxLevel int) []tocEntry {
+	var entries []tocEntry
+	seen := make(map[string]int)
+	inCode := false
+	for _, line := range splitLines(doc) {
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+		}
+		m := headingReg.FindStringSubmatch(line)
+		if inCode || m == nil {
+			continue
+		}
+		text := headingText(m[2])
+		anchor := githubSlug(text)
+		if n := seen[anchor]; n > 0 {
+			seen[anchor]++
+			anchor = fmt.Sprintf("%s-%d", anchor, n)
+		} else {
+			seen[anchor] = 1
+		}
+		if level := len(m[1]); level >= 2 && level <= maxLevel {
+			entries = append(entries, tocEntry{level: level, text: text, anchor: anchor})
+		}
+	}
+	return entries
+}
+
+// insertTOC puts an index of the version headings, nested under the group
+// headings if any, before the first of them
+func insertTOC(doc string, grouped bool) string {
+	maxLevel := 2
+	if grouped {
+		maxLevel = 3
+	}
+	entries := tocEntries(doc, maxLevel)
+	if len(entries) == 0 {
+		return doc
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s* [%s](#%s)\n", strings.Repeat("  ", e.level-2), escapeTitleMarkdown(e.text), e.anchor)
+	}
+	i := strings.Index(doc, "\n## ")
+	if strings.HasPrefix(doc, "## ") {
+		i = -1
+	} else if i < 0 {
+		return doc
+	}
+	return doc[:i+1] + b.String() + "\n" + doc[i+1:]
+}
@@ -0,0 +1,54 @@
+package ghch
+
+import "testing"
+
+func TestGitHubSlug(t *testing.T) {
+	testCases := map[string]string{
+		"v0.30.3 (2016-04-27)": "v0303-2016-04-27",
+		"Breaking Changes":     "breaking-changes",
+		"Über_Release 1.0!":    "über_release-10",
+	}
+	for text, expect := range testCases {
+		if got := githubSlug(text); got != expect {
+			t.Errorf("githubSlug(%q): got %q, expect %q", text, got, expect)
+		}
+	}
+}
+
+func TestInsertTOC(t *testing.T) {
+	doc := `# Changelog
+
+## [v0.2.0](https://github.com/o/r/releases/tag/v0.2.0) (2016-04-27)
+
+### Features
+
+## [v0.1.0](https://github.com/o/r/releases/tag/v0.1.0) (2016-04-21)
+
+### Features`
+	expect := `# Changelog
+
+* [v0.2.0 (2016-04-27)](#v020-2016-04-27)
+* [v0.1.0 (2016-04-21)](#v010-2016-04-21)
+
+## [v0.2.0](https://github.com/o/r/releases/tag/v0.2.0) (2016-04-27)
+
+### Features
+
+## [v0.1.0](https://github.com/o/r/releases/tag/v0.1.0) (2016-04-21)
+
+### Features`
+	if got := insertTOC(doc, false); got != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", got, expect)
+	}
+
+	grouped := "## 2016\n\n### v0.2.0\n\n#### Features\n\n### v0.1.0\n\n#### Features"
+	expect = "* [2016](#2016)\n  * [v0.2.0](#v020)\n  * [v0.1.0](#v010)\n\n" + grouped
+	if got := insertTOC(grouped, true); got != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", got, expect)
+	}
+
+	entries := tocEntries("## Unreleased\n\n### Features\n\n## v1\n\n### Features", 3)
+	if entries[3].anchor != "features-1" {
+		t.Errorf("duplicated headings should be suffixed: %v", entries)
+	}
+}
@@ -0,0 +1,134 @@
+package ghch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// translator translates titles of pull requests in a section at once by an
+// external command (exec) or an API endpoint (url). Both get
+// {"language": "ja", "texts": [...]} and return {"texts": [...]} in the
+// same order. Values of headers can refer to environment variables, to keep
+// tokens out of the config.
+type translator struct {
+	Exec     string            `yaml:"exec"`
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers"`
+	Language string            `yaml:"language"`
+}
+
+type translateRequest struct {
+	Language string   `json:"language,omitempty"`
+	Texts    []string `json:"texts"`
+}
+
+type translateResponse struct {
+	Texts []string `json:"texts"`
+}
+
+func (t *translator) validate() error {
+	if (t.Exec == "") == (t.URL == "") {
+		return errors.New("translate needs either exec or url")
+	}
+	return nil
+}
+
+func (t *translator) String() string {
+	if t.URL != "" {
+		return t.URL
+	}
+	return t.Exec
+}
+
+func (t *translator) translate(dir string, texts []string) ([]string, error) {
+	in, err := json.Marshal(translateRequest{Language: t.Language, Texts: texts})
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	if t.Exec != "" {
+		out, err = (&hook{Exec: t.Exec}).run(dir, nil, in)
+	} else {
+		out, err = t.post(in)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var res translateResponse
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, errors.Wrap(err, "failed to decode translations")
+	}
+	if len(res.Texts) != len(texts) {
+		return nil, errors.Errorf("got %d translations for %d titles", len(res.Texts), len(texts))
+	}
+	return res.Texts, nil
+}
+
+func (t *translator) post(in []byte) ([]byte, error) {
+	req, err := http.NewRequest("POST", t.URL, bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, errors.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
+
+// translateTitles replaces titles of pull requests with their translations,
+// keeping the originals in OriginalTitle. A failure leaves them as they were.
+func (gh *ghch) translateTitles(s *Section) {
+	var texts []string
+	seen := make(map[string]bool)
+	for _, pr := range s.PullRequests {
+		if pr.PullRequest != nil && !seen[pr.Title] {
+			seen[pr.Title] = true
+			texts = append(texts, pr.Title)
+		}
+	}
+	if len(texts) == 0 {
+		return
+	}
+	t := gh.config.Translate
+	translated, err := t.translate(gh.workDir(), texts)
+	if err != nil {
+		gh.fail(errors.Wrapf(err, "failed to translate titles by %s", t))
+		return
+	}
+	applyTranslations(s.PullRequests, texts, translated)
+}
+
+func applyTranslations(prs []*PullRequest, texts, translated []string) {
+	dict := make(map[string]string, len(texts))
+	for i, text := range texts {
+		dict[text] = translated[i]
+	}
+	for _, pr := range prs {
+		if pr.PullRequest == nil {
+			continue
+		}
+		if tr, ok := dict[pr.Title]; ok && tr != "" && tr != pr.Title {
+			pr.OriginalTitle = pr.Title
+			pr.Title = tr
+		}
+	}
+}
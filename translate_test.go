@@ -0,0 +1,40 @@
+package ghch
+
+import (
+	"testing"
+
+	"github.com/octokit/go-octokit/octokit"
+)
+
+func TestTranslator(t *testing.T) {
+	// echoes the request back with a title translated
+	tr := &translator{Exec: "sed s/Fix.typo/誤字を修正/", Language: "ja"}
+	got, err := tr.translate(".", []string{"Fix typo", "Add --all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "誤字を修正" || got[1] != "Add --all" {
+		t.Errorf("got %v", got)
+	}
+
+	if _, err := (&translator{Exec: "sed s/,.Add.--all.//"}).translate(".", []string{"Fix typo", "Add --all"}); err == nil {
+		t.Error("missing translations should be an error")
+	}
+	for _, bad := range []*translator{{}, {Exec: "trans", URL: "https://example.com"}} {
+		if err := bad.validate(); err == nil {
+			t.Errorf("translate needs either exec or url: %+v", bad)
+		}
+	}
+}
+
+func TestApplyTranslations(t *testing.T) {
+	fix := &PullRequest{PullRequest: &octokit.PullRequest{Number: 1, Title: "Fix typo"}}
+	same := &PullRequest{PullRequest: &octokit.PullRequest{Number: 2, Title: "README"}}
+	applyTranslations([]*PullRequest{fix, same}, []string{"Fix typo", "README"}, []string{"誤字を修正", "README"})
+	if fix.Title != "誤字を修正" || fix.OriginalTitle != "Fix typo" {
+		t.Errorf("got %+v", fix)
+	}
+	if same.OriginalTitle != "" {
+		t.Errorf("untranslated title should not have the original: %+v", same)
+	}
+}
@@ -0,0 +1,144 @@
+package ghch
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	versionsFromTags     = "tags"
+	versionsFromReleases = "releases"
+)
+
+func validVersionsFrom(from string) error {
+	switch from {
+	case "", versionsFromTags, versionsFromReleases:
+		return nil
+	}
+	return errors.Errorf("unknown --versions-from %q: must be tags or releases", from)
+}
+
+// releaseRef is a published GitHub Release used as a version
+type releaseRef struct {
+	TagName         string     `json:"tag_name"`
+	Name            string     `json:"name"`
+	TargetCommitish string     `json:"target_commitish"`
+	Draft           bool       `json:"draft"`
+	CreatedAt       time.Time  `json:"created_at"`
+	PublishedAt     *time.Time `json:"published_at"`
+}
+
+func (r *releaseRef) date() time.Time {
+	if r.PublishedAt != nil {
+		return *r.PublishedAt
+	}
+	return r.CreatedAt
+}
+
+// releaseRefs lists the published releases of the repository by their tags
+func (gh *ghch) releaseRefs() map[string]*releaseRef {
+	gh.releasesOnce.Do(func() {
+		owner, repo := gh.ownerAndRepo()
+		path := fmt.Sprintf("repos/%s/%s/releases?per_page=100", owner, repo)
+		var rels []*releaseRef
+		for path != "" {
+			var page []*releaseRef
+			var err error
+			if path, err = gh.apiGet(path, &page); err != nil {
+				gh.fail(errors.Wrap(err, "failed to list releases"))
+				break
+			}
+			rels = append(rels, page...)
+		}
+		gh.releases = make(map[string]*releaseRef)
+		for _, r := range rels {
+			if !r.Draft && r.TagName != "" {
+				gh.releases[r.TagName] = r
+			}
+		}
+	})
+	return gh.releases
+}
+
+// releaseVersions returns tags of the releases newest first by their dates,
+// since the tags may not be versions at all
+func releaseVersions(rels map[string]*releaseRef) []string {
+	vers := make([]string, 0, len(rels))
+	for tag := range rels {
+		vers = append(vers, tag)
+	}
+	sort.Slice(vers, func(i, j int) bool {
+		ti, tj := rels[vers[i]].date(), rels[vers[j]].date()
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return vers[i] > vers[j]
+	})
+	return vers
+}
+
+// localRevision returns rev as git knows it. A release whose tag has not
+// been fetched resolves to the commit of the tag.
+func (gh *ghch) localRevision(rev string) string {
+	if gh.versionsFrom != versionsFromReleases || rev == "" {
+		return rev
+	}
+	r, ok := gh.releaseRefs()[rev]
+	if !ok {
+		return rev
+	}
+	if _, ok := gh.tagRefs()[rev]; ok {
+		return rev
+	}
+	if sha, ok := gh.releaseCommits[rev]; ok {
+		return sha
+	}
+	sha, err := gh.releaseCommit(r)
+	if err != nil {
+		// reported once, and git fails on the unknown tag itself
+		gh.fail(err)
+		sha = rev
+	}
+	if gh.releaseCommits == nil {
+		gh.releaseCommits = make(map[string]string)
+	}
+	gh.releaseCommits[rev] = sha
+	return sha
+}
+
+var fullSHAReg = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// releaseCommit resolves the commit of the tag of the release with the API.
+// The target commitish is taken only when it is a SHA, because it is mostly
+// a branch name like "main", whose tip has moved on since the release.
+func (gh *ghch) releaseCommit(r *releaseRef) (string, error) {
+	sha := strings.TrimSpace(r.TargetCommitish)
+	if !fullSHAReg.MatchString(sha) {
+		owner, repo := gh.ownerAndRepo()
+		var ref struct {
+			Object struct {
+				Type string `json:"type"`
+				SHA  string `json:"sha"`
+			} `json:"object"`
+		}
+		if _, err := gh.apiGet(fmt.Sprintf("repos/%s/%s/git/refs/tags/%s", owner, repo, r.TagName), &ref); err != nil {
+			return "", errors.Wrapf(err, "failed to resolve the tag of release %s", r.TagName)
+		}
+		// annotated tags point to tag objects
+		if ref.Object.Type == "tag" {
+			if _, err := gh.apiGet(fmt.Sprintf("repos/%s/%s/git/tags/%s", owner, repo, ref.Object.SHA), &ref); err != nil {
+				return "", errors.Wrapf(err, "failed to resolve the tag of release %s", r.TagName)
+			}
+		}
+		sha = ref.Object.SHA
+	}
+	if _, err := gh.cmd("rev-parse", "--verify", "--quiet", sha+"^{commit}"); err != nil {
+		return "", errors.Errorf("commit %s of release %s is not in the local history. run `git fetch --tags`", sha, r.TagName)
+	}
+	return sha, nil
+}
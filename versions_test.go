@@ -0,0 +1,55 @@
+package ghch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Songmu/ghch/ghchtest"
+)
+
+func TestReleaseVersions(t *testing.T) {
+	at := func(day int) *time.Time {
+		t := time.Date(2021, 4, day, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	rels := map[string]*releaseRef{
+		"v1.0.0":       {TagName: "v1.0.0", PublishedAt: at(1)},
+		"release-2021": {TagName: "release-2021", PublishedAt: at(20)},
+		"v1.1.0":       {TagName: "v1.1.0", PublishedAt: at(10)},
+		// not published yet
+		"v1.2.0-rc": {TagName: "v1.2.0-rc", CreatedAt: *at(15)},
+	}
+	expect := []string{"release-2021", "v1.2.0-rc", "v1.1.0", "v1.0.0"}
+	if got := releaseVersions(rels); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v, expect %v", got, expect)
+	}
+}
+
+func TestLocalRevisionOfRelease(t *testing.T) {
+	srv := ghchtest.NewServer()
+	defer srv.Close()
+	repo := ghchtest.NewRepo(t, "Songmu", "ghch")
+	released := repo.MergePR(1, "alice", "Add a feature").MergeCommitSHA
+	// the tip of the branch moved on after the releases, whose tags are not fetched
+	repo.MergePR(2, "bob", "Fix a crash")
+	srv.AddRelease("Songmu", "ghch", ghchtest.Release{TagName: "v0.1.0", Commit: released})
+
+	gh := (&ghch{repoPath: repo.Dir, gitPath: "git", apiURL: srv.APIURL(), versionsFrom: versionsFromReleases}).initialize()
+	gh.releasesOnce.Do(func() {
+		gh.releases = map[string]*releaseRef{
+			"v0.1.0": {TagName: "v0.1.0", TargetCommitish: "master"},
+			"v0.0.1": {TagName: "v0.0.1", TargetCommitish: released},
+			"v0.0.0": {TagName: "v0.0.0", TargetCommitish: "master"},
+		}
+	})
+	if got := gh.localRevision("v0.1.0"); got != released {
+		t.Errorf("branch names should not be taken as the commit: got %s, expect %s", got, released)
+	}
+	if got := gh.localRevision("v0.0.1"); got != released {
+		t.Errorf("got %s, expect %s", got, released)
+	}
+	if got := gh.localRevision("v0.0.0"); got != "v0.0.0" || !gh.failed {
+		t.Errorf("unresolvable tags should fail: got %s", got)
+	}
+}